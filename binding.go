@@ -0,0 +1,102 @@
+package gviper
+
+import (
+	"github.com/ace-zhaoy/errors"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+	"reflect"
+)
+
+// binder decodes a config into a fresh value and returns it with a commit func
+// that publishes it. Nothing is published when decoding or validation fails,
+// so a bad reload never leaves a bound value half-updated.
+type binder interface {
+	prepare(v *viper.Viper, decoderConfigOptions ...viper.DecoderConfigOption) (data any, commit func(), err error)
+	dataType() reflect.Type
+}
+
+type binding struct {
+	binder               binder
+	tagName              string
+	decoderConfigOptions []viper.DecoderConfigOption
+}
+
+func newBinding(binder binder, tagName string, decoderConfigOptions ...viper.DecoderConfigOption) *binding {
+	return &binding{
+		binder:  binder,
+		tagName: tagName,
+		decoderConfigOptions: append([]viper.DecoderConfigOption{
+			func(dc *mapstructure.DecoderConfig) { dc.TagName = tagName }}, decoderConfigOptions...),
+	}
+}
+
+// prepare decodes with the global options followed by the binding's own. When
+// WarnUnusedKeys is in effect, i.e. the options leave a Metadata to collect
+// into, it also returns the keys left unused, non-nil even if every key was used.
+func (b *binding) prepare(v *viper.Viper, decoderConfigOptions []viper.DecoderConfigOption) (any, func(), []string, error) {
+	var md *mapstructure.Metadata
+	opts := append([]viper.DecoderConfigOption(nil), decoderConfigOptions...)
+	opts = append(opts, b.decoderConfigOptions...)
+	opts = append(opts, func(dc *mapstructure.DecoderConfig) { md = dc.Metadata })
+	data, commit, err := b.binder.prepare(v, opts...)
+	if err != nil || md == nil {
+		return data, commit, nil, err
+	}
+	return data, commit, append([]string{}, md.Unused...), nil
+}
+
+// bindEnv binds every field of the bound type to its environment variable, so
+// env overrides also reach fields whose keys are absent from the file.
+func (b *binding) bindEnv(v *viper.Viper) {
+	walkFields(b.binder.dataType(), b.tagName, "", func(path string, _ reflect.StructField) {
+		_ = v.BindEnv(path)
+	})
+}
+
+// applyDefaults registers the `default` tag of every field as a viper default,
+// so fields absent from the file, or removed from it on reload, get that value.
+func (b *binding) applyDefaults(v *viper.Viper) {
+	walkFields(b.binder.dataType(), b.tagName, "", func(path string, f reflect.StructField) {
+		if value, ok := f.Tag.Lookup("default"); ok {
+			v.SetDefault(path, value)
+		}
+	})
+}
+
+type pointerBinder struct {
+	data     reflect.Value
+	baseline reflect.Value
+}
+
+func newPointerBinder(data any) *pointerBinder {
+	p := &pointerBinder{data: reflect.ValueOf(data)}
+	if p.data.Kind() == reflect.Ptr && !p.data.IsNil() {
+		p.baseline = reflect.New(p.data.Elem().Type()).Elem()
+		p.baseline.Set(p.data.Elem())
+	}
+	return p
+}
+
+func (p *pointerBinder) dataType() reflect.Type {
+	if !p.baseline.IsValid() {
+		return p.data.Type()
+	}
+	return p.baseline.Type()
+}
+
+// prepare decodes into a copy of the value the target held when it was bound,
+// so keys removed from the file fall back to that value instead of keeping
+// whatever the previous load wrote.
+func (p *pointerBinder) prepare(v *viper.Viper, decoderConfigOptions ...viper.DecoderConfigOption) (any, func(), error) {
+	if !p.baseline.IsValid() {
+		return nil, nil, errors.NewWithMessage("bind target must be a non-nil pointer, got %s", p.data.Kind())
+	}
+	fresh := reflect.New(p.baseline.Type())
+	fresh.Elem().Set(p.baseline)
+	opts := append([]viper.DecoderConfigOption{
+		func(dc *mapstructure.DecoderConfig) { dc.ZeroFields = true }}, decoderConfigOptions...)
+	if err := v.Unmarshal(fresh.Interface(), opts...); err != nil {
+		return nil, nil, err
+	}
+	return fresh.Interface(), func() { p.data.Elem().Set(fresh.Elem()) }, nil
+}
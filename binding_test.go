@@ -0,0 +1,201 @@
+package gviper
+
+import (
+	"context"
+	"github.com/ace-zhaoy/errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfig_Bind_removedKeyResets(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper\nenv: test\ntags: [a, b]"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	type MyServer struct {
+		Name string   `json:"name"`
+		Env  string   `json:"env"`
+		Tags []string `json:"tags"`
+	}
+	myServer := MyServer{Env: "dev", Tags: []string{"default"}}
+
+	config := NewConfig(d)
+	config.Bind("server", &myServer)
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, MyServer{Name: "gviper", Env: "test", Tags: []string{"a", "b"}}, myServer)
+
+	err = os.WriteFile(serverConfigFile, []byte("name: gviper2"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, MyServer{Name: "gviper2", Env: "dev", Tags: []string{"default"}}, myServer)
+}
+
+func TestConfig_Bind_failedDecodeKeepsLastGood(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper\nport: 8080"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	type MyServer struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+	type MyName struct {
+		Name string `json:"name"`
+	}
+	var myServer MyServer
+	config := NewConfig(d)
+	myName := BindValue[MyName](config, "server")
+	config.Bind("server", &myServer)
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	err = os.WriteFile(serverConfigFile, []byte("name: broken\nport: noport"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	err = config.Load()
+	assert.True(t, errors.Is(err, errors.NewWithMessage("unmarshal config [%s] failed", "server")))
+	assert.Equal(t, MyServer{Name: "gviper", Port: 8080}, myServer)
+	assert.Equal(t, &MyName{Name: "gviper"}, myName.Load())
+	assert.Equal(t, "gviper", config.GetString("server.name"))
+}
+
+func TestConfig_Bind_failedReloadNotifies(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("port: 8080"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	type MyServer struct {
+		Port int `json:"port"`
+	}
+	ch := make(chan error, 1)
+	config := NewConfig(d)
+	server := BindValue[MyServer](config, "server")
+	config.RegisterNotification(notificationFunc(func(configName string, err error) { ch <- err }))
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+
+	err = replaceFile(serverConfigFile, []byte("port: noport"))
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	select {
+	case err = <-ch:
+		assert.True(t, errors.Is(err, errors.NewWithMessage("unmarshal config [%s] failed", "server")))
+	case <-time.After(time.Second):
+		t.Fatal("Expected notification")
+	}
+	assert.Equal(t, &MyServer{Port: 8080}, server.Load())
+}
+
+func TestConfig_Bind_nonPointer(t *testing.T) {
+	d := t.TempDir()
+	err := os.WriteFile(filepath.Join(d, "server.yaml"), []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	type MyServer struct {
+		Name string `json:"name"`
+	}
+	config := NewConfig(d)
+	config.Bind("server", MyServer{})
+	assert.Error(t, config.Load())
+}
+
+func replaceFile(name string, data []byte) error {
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+func TestConfig_Bind_defaultTag(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper\ntimeout: 5s\nhttp:\n  port: 9090"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	type HTTP struct {
+		Host string `yaml:"host" default:"0.0.0.0"`
+		Port int    `yaml:"port" default:"8080"`
+	}
+	type MyServer struct {
+		Name    string        `yaml:"name" default:"unnamed"`
+		Timeout time.Duration `yaml:"timeout" default:"30s"`
+		Tags    []string      `yaml:"tags" default:"a,b"`
+		HTTP    HTTP          `yaml:"http"`
+	}
+	var myServer MyServer
+
+	config := NewConfig(d)
+	config.BindWithTag("server", &myServer, "yaml")
+	server := BindValueWithTag[MyServer](config, "server", "yaml")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	want := MyServer{
+		Name:    "gviper",
+		Timeout: 5 * time.Second,
+		Tags:    []string{"a", "b"},
+		HTTP:    HTTP{Host: "0.0.0.0", Port: 9090},
+	}
+	assert.Equal(t, want, myServer)
+	assert.Equal(t, &want, server.Load())
+	assert.Equal(t, "0.0.0.0", config.GetString("server.http.host"))
+
+	err = os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	want = MyServer{
+		Name:    "gviper",
+		Timeout: 30 * time.Second,
+		Tags:    []string{"a", "b"},
+		HTTP:    HTTP{Host: "0.0.0.0", Port: 8080},
+	}
+	assert.Equal(t, want, myServer)
+	assert.Equal(t, &want, server.Load())
+}
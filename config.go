@@ -1,12 +1,17 @@
 package gviper
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"github.com/ace-zhaoy/errors"
 	"github.com/ace-zhaoy/go-utils/uslice"
-	"github.com/fsnotify/fsnotify"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,6 +20,7 @@ func Default(configPath string) *Config {
 		WithConfigPath(configPath),
 		WithDecoderConfigOptions(func(dc *mapstructure.DecoderConfig) {
 			dc.DecodeHook = mapstructure.ComposeDecodeHookFunc(
+				mapstructure.TextUnmarshallerHookFunc(),
 				dc.DecodeHook,
 				mapstructure.StringToTimeHookFunc(time.RFC3339),
 			)
@@ -22,44 +28,99 @@ func Default(configPath string) *Config {
 	)
 }
 
-type Listener func(viper *viper.Viper) error
-
 type configParam struct {
-	configName           string
-	configType           string
-	configFile           string
-	tagName              string
-	viper                *viper.Viper
-	decoderConfigOptions []viper.DecoderConfigOption
-	onChange             Listener
-	data                 any
-}
+	configName string
+	configType string
+	configFile string
+	optional   bool
+	loaded     bool
+	failures   int
+	viper      *viper.Viper
+	settings   map[string]any
+	// checksum is the sha256 of the content last read, to skip unchanged
+	// files; version is that of the content last loaded successfully.
+	checksum   [sha256.Size]byte
+	version    [sha256.Size]byte
+	bindings   []*binding
+	validators []Validator
+	listeners  []*listener
+	// loadMu serializes the loads of this config, listeners and report
+	// included, so events are delivered in the order they were applied.
+	// It is taken before Config.mu.
+	loadMu *sync.Mutex
+}
+
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// LoadPolicy decides what Load does when a config file fails to load.
+type LoadPolicy int
+
+const (
+	// LoadFailFast stops at the first failing file.
+	LoadFailFast LoadPolicy = iota
+	// LoadContinue loads every file and returns the errors of all failing ones.
+	LoadContinue
+)
+
+// RemovePolicy decides what a watched config does when its file is deleted.
+type RemovePolicy int
+
+const (
+	// RemoveKeep keeps the last loaded values.
+	RemoveKeep RemovePolicy = iota
+	// RemoveReset reloads the config as empty, so bound values fall back to
+	// their defaults.
+	RemoveReset
+)
 
 type Config struct {
-	viper                *viper.Viper
+	viper                atomic.Pointer[viper.Viper]
+	automaticEnv         bool
+	allowEmptyEnv        bool
+	envPrefix            string
 	configPath           string
 	defaultConfigType    string
 	configs              []*configParam
 	notifications        []Notification
+	eventNotifiers       []EventNotifier
+	notifyQueueSize      int
+	notifyWorkers        int
+	notifyOverflow       OverflowPolicy
+	dispatchMu           sync.Mutex
+	dispatcher           *dispatcher
 	decoderConfigOptions []viper.DecoderConfigOption
+	required             []string
+	loadPolicy           LoadPolicy
+	removePolicy         RemovePolicy
+	listenerMu           sync.RWMutex
+	listenerSeq          uint64
+	mu                   sync.Mutex
+	watchMu              sync.Mutex
+	watcher              *watcher
+	signalReloaders      []*signalReloader
+	watchDebounce        time.Duration
 }
 
 func NewConfig(configPath string, names ...string) *Config {
 	c := &Config{
-		viper:             viper.New(),
 		configPath:        configPath,
 		defaultConfigType: "yaml",
+		notifyQueueSize:   256,
+		notifyWorkers:     1,
 	}
+	c.viper.Store(viper.New())
 	c.Register(names...)
 	return c
 }
 
 func NewConfigWithOptions(options ...Option) *Config {
 	c := &Config{
-		viper:             viper.New(),
 		configPath:        ".",
 		defaultConfigType: "yaml",
+		notifyQueueSize:   256,
+		notifyWorkers:     1,
 	}
+	c.viper.Store(viper.New())
 	for _, option := range options {
 		option(c)
 	}
@@ -67,11 +128,50 @@ func NewConfigWithOptions(options ...Option) *Config {
 }
 
 func (c *Config) AutomaticEnv() {
-	c.viper.AutomaticEnv()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.automaticEnv = true
+	c.publish()
 }
 
 func (c *Config) AllowEmptyEnv(allowEmptyEnv bool) {
-	c.viper.AllowEmptyEnv(allowEmptyEnv)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.allowEmptyEnv = allowEmptyEnv
+	c.publish()
+}
+
+// SetEnvPrefix sets the prefix of environment variables read by AutomaticEnv.
+// Keys are namespaced by config, so with prefix "APP" the key "database.port"
+// is read from APP_DATABASE_PORT.
+func (c *Config) SetEnvPrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.envPrefix = prefix
+	c.publish()
+}
+
+func (c *Config) applyEnv(v *viper.Viper, prefix string) {
+	if !c.automaticEnv {
+		return
+	}
+	v.SetEnvPrefix(prefix)
+	v.SetEnvKeyReplacer(envKeyReplacer)
+	v.AutomaticEnv()
+	v.AllowEmptyEnv(c.allowEmptyEnv)
+}
+
+// publish swaps in a new read-only snapshot of every loaded config, so that
+// getters never observe a viper that is being written to. Callers hold c.mu.
+func (c *Config) publish() {
+	v := viper.New()
+	c.applyEnv(v, c.envPrefix)
+	uslice.ForEach(c.configs, func(cp *configParam) {
+		if cp.settings != nil {
+			v.Set(cp.configName, cp.settings)
+		}
+	})
+	c.viper.Store(v)
 }
 
 func (c *Config) parseName(name string) (configName string, configType string, configFile string) {
@@ -92,26 +192,47 @@ func (c *Config) find(configName string) *configParam {
 	return nil
 }
 
+// findByKey returns the config a dotted key belongs to, preferring the
+// longest matching config name.
+func (c *Config) findByKey(key string) *configParam {
+	key = strings.ToLower(key)
+	var cp *configParam
+	uslice.ForEach(c.configs, func(v *configParam) {
+		name := strings.ToLower(v.configName)
+		if (key == name || strings.HasPrefix(key, name+".")) &&
+			(cp == nil || len(v.configName) > len(cp.configName)) {
+			cp = v
+		}
+	})
+	return cp
+}
+
 func (c *Config) add(configName string, configType string, configFile string) *configParam {
 	if cp := c.find(configName); cp != nil {
 		return cp
 	}
-	v := viper.New()
-	v.SetConfigName(configName)
-	v.SetConfigType(configType)
-	v.SetConfigFile(configFile)
 	cp := &configParam{
 		configName: configName,
 		configType: configType,
 		configFile: configFile,
-		viper:      v,
+		loadMu:     &sync.Mutex{},
 	}
+	cp.viper = c.newViper(cp)
 
 	c.configs = append(c.configs, cp)
 
 	return cp
 }
 
+func (c *Config) newViper(cp *configParam) *viper.Viper {
+	v := viper.New()
+	v.SetConfigName(cp.configName)
+	v.SetConfigType(cp.configType)
+	v.SetConfigFile(cp.configFile)
+	c.applyEnv(v, strings.TrimPrefix(c.envPrefix+"_"+cp.configName, "_"))
+	return v
+}
+
 func (c *Config) resolveConfigParam(name string) *configParam {
 	configName, fileType, filePath := c.parseName(name)
 	return c.add(configName, fileType, filePath)
@@ -121,9 +242,10 @@ func (c *Config) Register(names ...string) {
 	uslice.ForEach(names, func(name string) { c.resolveConfigParam(name) })
 }
 
-func (c *Config) OnChange(name string, listener Listener) {
-	cp := c.resolveConfigParam(name)
-	cp.onChange = listener
+// RegisterOptional registers config files that may be absent. A missing
+// optional file loads as an empty config instead of failing Load.
+func (c *Config) RegisterOptional(names ...string) {
+	uslice.ForEach(names, func(name string) { c.resolveConfigParam(name).optional = true })
 }
 
 func (c *Config) Bind(name string, data any) {
@@ -131,275 +253,428 @@ func (c *Config) Bind(name string, data any) {
 }
 
 func (c *Config) BindWithTag(name string, data any, tagName string, decoderConfigOptions ...viper.DecoderConfigOption) {
+	c.bind(name, newPointerBinder(data), tagName, decoderConfigOptions...)
+}
+
+func (c *Config) bind(name string, binder binder, tagName string, decoderConfigOptions ...viper.DecoderConfigOption) {
 	cp := c.resolveConfigParam(name)
-	cp.data = data
-	cp.decoderConfigOptions = append([]viper.DecoderConfigOption{
-		func(dc *mapstructure.DecoderConfig) { dc.TagName = tagName }}, decoderConfigOptions...)
+	cp.bindings = append(cp.bindings, newBinding(binder, tagName, decoderConfigOptions...))
 }
 
-func (c *Config) BindAndListen(name string, data any, listener Listener) {
+func (c *Config) BindAndListen(name string, data any, listener Listener) *Subscription {
 	c.Bind(name, data)
-	c.OnChange(name, listener)
+	return c.OnChange(name, listener)
 }
 
 func (c *Config) RegisterNotification(notifications ...Notification) {
 	c.notifications = append(c.notifications, notifications...)
 }
 
-func (c *Config) notify(configName string, err error) {
-	uslice.ForEach(c.notifications, func(n Notification) { n.Notify(configName, err) })
+func (c *Config) reload(cp *configParam) error {
+	return c.load(cp, true)
+}
+
+// load reads the file into a fresh viper and applies it. Unless force is set,
+// a file whose content did not change since the last attempt is skipped.
+// Listeners and notifiers run after c.mu is released, so they may call back
+// into the Config, but not reload the config they were called for.
+func (c *Config) load(cp *configParam, force bool) error {
+	cp.loadMu.Lock()
+	defer cp.loadMu.Unlock()
+	start := time.Now()
+	skipped, result, err := c.loadLocked(cp, force)
+	if skipped {
+		return nil
+	}
+	return c.finish(cp, start, result, err)
+}
+
+func (c *Config) loadLocked(cp *configParam, force bool) (skipped bool, result *applyResult, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer errors.Recover(func(e error) { err = e })
+	data, err := c.readFile(cp)
+	missing := cp.optional && os.IsNotExist(err)
+	if missing {
+		err = nil
+	}
+	errors.Check(errors.Wrap(err, "read config [%s] error", cp.configName))
+	checksum := sha256.Sum256(data)
+	if !force && checksum == cp.checksum {
+		return true, nil, nil
+	}
+	cp.checksum = checksum
+
+	v := c.newViper(cp)
+	if !missing {
+		errors.Check(errors.Wrap(v.ReadConfig(bytes.NewReader(data)), "read config [%s] error", cp.configName))
+	}
+	result, err = c.apply(cp, v, ChangeUpdated, checksum)
+	return false, result, err
+}
+
+// remove handles the deletion of a loaded file according to the remove policy.
+// Listeners receive a ChangeRemoved event either way.
+func (c *Config) remove(cp *configParam) error {
+	cp.loadMu.Lock()
+	defer cp.loadMu.Unlock()
+	start := time.Now()
+	skipped, result, err := c.removeLocked(cp)
+	if skipped {
+		return nil
+	}
+	return c.finish(cp, start, result, err)
 }
 
-func (c *Config) buildChangeFunc(cp *configParam) func() error {
-	return func() (err error) {
-		defer errors.Recover(func(e error) { err = e })
-		c.viper.Set(cp.configName, cp.viper.AllSettings())
-		if cp.data != nil {
-			opts := append([]viper.DecoderConfigOption(nil), c.decoderConfigOptions...)
-			opts = append(opts, cp.decoderConfigOptions...)
-			err = cp.viper.Unmarshal(cp.data, opts...)
-			errors.Check(errors.Wrap(err, "unmarshal config [%s] failed", cp.configName))
+func (c *Config) removeLocked(cp *configParam) (skipped bool, result *applyResult, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer errors.Recover(func(e error) { err = e })
+	if cp.checksum == [sha256.Size]byte{} {
+		return true, nil, nil
+	}
+	cp.checksum = [sha256.Size]byte{}
+	if c.removePolicy == RemoveReset {
+		result, err = c.apply(cp, c.newViper(cp), ChangeRemoved, [sha256.Size]byte{})
+		return false, result, err
+	}
+	event := newChangeEvent(cp.configName, cp.settings, cp.settings, cp.viper)
+	event.Kind = ChangeRemoved
+	return false, &applyResult{event: event, listeners: c.getListeners(cp)}, nil
+}
+
+// applyResult is what a successful apply leaves to be delivered once c.mu is
+// released.
+type applyResult struct {
+	event     *ChangeEvent
+	listeners []*listener
+	notices   []*NotifyEvent
+}
+
+// finish delivers the outcome of a load outside c.mu: notices first, then
+// the listeners and finally the load report.
+func (c *Config) finish(cp *configParam, start time.Time, result *applyResult, err error) error {
+	var event *ChangeEvent
+	if result != nil {
+		event = result.event
+		uslice.ForEach(result.notices, c.dispatch)
+		err = c.fireListeners(cp, event, result.listeners)
+	}
+	c.report(cp, start, event, err)
+	return err
+}
+
+// apply validates v and decodes every binding before anything is published.
+// The previous settings and bound values stay live when validating or
+// decoding fails, and so does the version. Callers hold c.mu.
+func (c *Config) apply(cp *configParam, v *viper.Viper, kind ChangeKind, version [sha256.Size]byte) (result *applyResult, err error) {
+	defer errors.Recover(func(e error) { err = e })
+	uslice.ForEach(cp.bindings, func(b *binding) {
+		b.applyDefaults(v)
+		if c.automaticEnv {
+			b.bindEnv(v)
 		}
-		if cp.onChange != nil {
-			err = cp.onChange(cp.viper)
-			errors.Check(errors.Wrap(err, "onchange config [%s] failed", cp.configName))
+	})
+	errors.Check(c.runValidators(cp, v))
+	commits := make([]func(), 0, len(cp.bindings))
+	// unused holds the keys no WarnUnusedKeys binding decoded; nil if none warns
+	var unused []string
+	uslice.ForEach(cp.bindings, func(b *binding) {
+		value, commit, keys, err := b.prepare(v, c.decoderConfigOptions)
+		errors.Check(errors.Wrap(err, "unmarshal config [%s] failed", cp.configName))
+		errors.Check(errors.Wrap(c.validateData(cp, b, value), "validate config [%s] failed", cp.configName))
+		commits = append(commits, commit)
+		if keys != nil && unused != nil {
+			keys = uslice.Intersect(unused, keys)
 		}
-		return
+		if keys != nil {
+			unused = keys
+		}
+	})
+
+	event := newChangeEvent(cp.configName, cp.settings, v.AllSettings(), v)
+	event.Kind = kind
+	cp.viper = v
+	cp.settings = event.Current
+	cp.version = version
+	c.publish()
+	uslice.ForEach(commits, func(commit func()) { commit() })
+	result = &applyResult{event: event, listeners: c.getListeners(cp)}
+	if len(unused) > 0 {
+		result.notices = append(result.notices, newNotifyEvent(NotifyUnusedKeys, cp, newUnusedKeysError(cp, unused)))
 	}
+	return result, nil
 }
 
+func (c *Config) readFile(cp *configParam) ([]byte, error) {
+	if !uslice.Contains(viper.SupportedExts, cp.configType) {
+		return nil, viper.UnsupportedConfigError(cp.configType)
+	}
+	return os.ReadFile(cp.configFile)
+}
+
+// Load loads every registered config file. With LoadContinue the remaining
+// files are still loaded after a failure and the errors of every failing file
+// are returned joined.
 func (c *Config) Load() (err error) {
 	defer errors.Recover(func(e error) { err = e })
-	uslice.ForEach(c.configs, func(cp *configParam) {
-		errors.Check(errors.Wrap(cp.viper.ReadInConfig(), "read config [%s] error", cp.configName))
-		errors.Check(c.buildChangeFunc(cp)())
-	})
-	return nil
+	if c.loadPolicy == LoadFailFast {
+		uslice.ForEach(c.configs, func(cp *configParam) {
+			errors.Check(c.reload(cp))
+		})
+		errors.Check(c.checkRequired())
+		return nil
+	}
+	errs := uslice.Map(c.configs, c.reload)
+	return errors.Join(append(errs, c.checkRequired())...)
+}
+
+// Reload re-reads, validates and re-binds a single config and fires its
+// listeners before returning, even if the file did not change. A failure is
+// returned and also sent to the notifications.
+func (c *Config) Reload(name string) error {
+	configName, _, _ := c.parseName(name)
+	cp := c.find(configName)
+	if cp == nil {
+		return errors.NewWithMessage("config [%s] not registered", configName)
+	}
+	return c.reload(cp)
 }
 
-func (c *Config) Watch() {
-	uslice.ForEach(c.configs, func(cp *configParam) {
-		cp.viper.OnConfigChange(func(_ fsnotify.Event) {
-			defer errors.Recover(func(e error) {
-				c.notify(cp.configName, e)
-			})
-			errors.Check(errors.Wrap(cp.viper.ReadInConfig(), "read config [%s] error", cp.configName))
-			errors.Check(c.buildChangeFunc(cp)())
-		})
-		cp.viper.WatchConfig()
-	})
+// ReloadAll reloads every registered config like Reload and returns the
+// errors of all failing ones.
+func (c *Config) ReloadAll() error {
+	return errors.Join(uslice.Map(c.configs, c.reload)...)
 }
 
 func (c *Config) Has(key string) bool {
-	return c.viper.IsSet(key)
+	return c.viper.Load().IsSet(key)
 }
 
 func (c *Config) Get(key string) any {
-	return c.viper.Get(key)
+	return c.viper.Load().Get(key)
 }
 
 func (c *Config) IsSet(key string) bool {
-	return c.viper.IsSet(key)
+	return c.viper.Load().IsSet(key)
 }
 
 func (c *Config) GetString(key string) string {
-	return c.viper.GetString(key)
+	return c.viper.Load().GetString(key)
 }
 
 func (c *Config) GetBool(key string) bool {
-	return c.viper.GetBool(key)
+	return c.viper.Load().GetBool(key)
 }
 
 func (c *Config) GetInt(key string) int {
-	return c.viper.GetInt(key)
+	return c.viper.Load().GetInt(key)
 }
 
 func (c *Config) GetInt32(key string) int32 {
-	return c.viper.GetInt32(key)
+	return c.viper.Load().GetInt32(key)
 }
 
 func (c *Config) GetInt64(key string) int64 {
-	return c.viper.GetInt64(key)
+	return c.viper.Load().GetInt64(key)
 }
 
 func (c *Config) GetUint(key string) uint {
-	return c.viper.GetUint(key)
+	return c.viper.Load().GetUint(key)
 }
 
 func (c *Config) GetUint32(key string) uint32 {
-	return c.viper.GetUint32(key)
+	return c.viper.Load().GetUint32(key)
 }
 
 func (c *Config) GetUint64(key string) uint64 {
-	return c.viper.GetUint64(key)
+	return c.viper.Load().GetUint64(key)
 }
 
 func (c *Config) GetFloat64(key string) float64 {
-	return c.viper.GetFloat64(key)
+	return c.viper.Load().GetFloat64(key)
 }
 
 func (c *Config) GetTime(key string) time.Time {
-	return c.viper.GetTime(key)
+	return c.viper.Load().GetTime(key)
 }
 
 func (c *Config) GetDuration(key string) time.Duration {
-	return c.viper.GetDuration(key)
+	return c.viper.Load().GetDuration(key)
 }
 
 func (c *Config) GetIntSlice(key string) []int {
-	return c.viper.GetIntSlice(key)
+	return c.viper.Load().GetIntSlice(key)
 }
 
 func (c *Config) GetStringSlice(key string) []string {
-	return c.viper.GetStringSlice(key)
+	return c.viper.Load().GetStringSlice(key)
 }
 
 func (c *Config) GetStringMap(key string) map[string]any {
-	return c.viper.GetStringMap(key)
+	return c.viper.Load().GetStringMap(key)
 }
 
 func (c *Config) GetStringMapString(key string) map[string]string {
-	return c.viper.GetStringMapString(key)
+	return c.viper.Load().GetStringMapString(key)
 }
 
 func (c *Config) GetStringMapStringSlice(key string) map[string][]string {
-	return c.viper.GetStringMapStringSlice(key)
+	return c.viper.Load().GetStringMapStringSlice(key)
 }
 
 func (c *Config) GetSizeInBytes(key string) uint {
-	return c.viper.GetSizeInBytes(key)
+	return c.viper.Load().GetSizeInBytes(key)
 }
 
 func (c *Config) Sub(key string) *viper.Viper {
-	return c.viper.Sub(key)
+	return c.viper.Load().Sub(key)
 }
 
 func (c *Config) AllSettings() map[string]any {
-	return c.viper.AllSettings()
+	return c.viper.Load().AllSettings()
 }
 
 func (c *Config) Default(key string, defaultValue any) any {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.Get(key)
+	return v.Get(key)
 }
 
 func (c *Config) DefaultString(key string, defaultValue string) string {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetString(key)
+	return v.GetString(key)
 }
 
 func (c *Config) DefaultBool(key string, defaultValue bool) bool {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetBool(key)
+	return v.GetBool(key)
 }
 
 func (c *Config) DefaultInt(key string, defaultValue int) int {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetInt(key)
+	return v.GetInt(key)
 }
 
 func (c *Config) DefaultInt32(key string, defaultValue int32) int32 {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetInt32(key)
+	return v.GetInt32(key)
 }
 
 func (c *Config) DefaultInt64(key string, defaultValue int64) int64 {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetInt64(key)
+	return v.GetInt64(key)
 }
 
 func (c *Config) DefaultUint(key string, defaultValue uint) uint {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetUint(key)
+	return v.GetUint(key)
 }
 
 func (c *Config) DefaultUint32(key string, defaultValue uint32) uint32 {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetUint32(key)
+	return v.GetUint32(key)
 }
 
 func (c *Config) DefaultUint64(key string, defaultValue uint64) uint64 {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetUint64(key)
+	return v.GetUint64(key)
 }
 
 func (c *Config) DefaultFloat64(key string, defaultValue float64) float64 {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetFloat64(key)
+	return v.GetFloat64(key)
 }
 
 func (c *Config) DefaultTime(key string, defaultValue time.Time) time.Time {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetTime(key)
+	return v.GetTime(key)
 }
 
 func (c *Config) DefaultDuration(key string, defaultValue time.Duration) time.Duration {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetDuration(key)
+	return v.GetDuration(key)
 }
 
 func (c *Config) DefaultIntSlice(key string, defaultValue []int) []int {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetIntSlice(key)
+	return v.GetIntSlice(key)
 }
 
 func (c *Config) DefaultStringSlice(key string, defaultValue []string) []string {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetStringSlice(key)
+	return v.GetStringSlice(key)
 }
 
 func (c *Config) DefaultStringMap(key string, defaultValue map[string]any) map[string]any {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetStringMap(key)
+	return v.GetStringMap(key)
 }
 
 func (c *Config) DefaultStringMapString(key string, defaultValue map[string]string) map[string]string {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetStringMapString(key)
+	return v.GetStringMapString(key)
 }
 
 func (c *Config) DefaultStringMapStringSlice(key string, defaultValue map[string][]string) map[string][]string {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetStringMapStringSlice(key)
+	return v.GetStringMapStringSlice(key)
 }
 
 func (c *Config) DefaultSizeInBytes(key string, defaultValue uint) uint {
-	if !c.viper.IsSet(key) {
+	v := c.viper.Load()
+	if !v.IsSet(key) {
 		return defaultValue
 	}
-	return c.viper.GetSizeInBytes(key)
+	return v.GetSizeInBytes(key)
 }
@@ -1,11 +1,14 @@
 package gviper
 
 import (
+	"context"
+	"fmt"
 	"github.com/ace-zhaoy/errors"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -53,15 +56,15 @@ func TestConfig_AutomaticEnv(t *testing.T) {
 	t.Setenv("TEST_ENV", "test")
 	t.Setenv("TEST_ENV_2", "")
 
-	if config.viper.Get("test_env") != "test" {
-		t.Errorf("Expected test_env to be test, got %s", config.viper.Get("test_env"))
+	if config.viper.Load().Get("test_env") != "test" {
+		t.Errorf("Expected test_env to be test, got %s", config.viper.Load().Get("test_env"))
 	}
-	if config.viper.Get("test_env_2") != nil {
-		t.Errorf("Expected test_env_2 to be nil, got %s", config.viper.Get("test_env_2"))
+	if config.viper.Load().Get("test_env_2") != nil {
+		t.Errorf("Expected test_env_2 to be nil, got %s", config.viper.Load().Get("test_env_2"))
 	}
 
-	if config.viper.Get("test_env_3") != nil {
-		t.Errorf("Expected test_env_3 to be nil, got %s", config.viper.Get("test_env_3"))
+	if config.viper.Load().Get("test_env_3") != nil {
+		t.Errorf("Expected test_env_3 to be nil, got %s", config.viper.Load().Get("test_env_3"))
 	}
 }
 
@@ -72,15 +75,15 @@ func TestConfig_AllowEmptyEnv(t *testing.T) {
 	t.Setenv("TEST_ENV", "test")
 	t.Setenv("TEST_ENV_2", "")
 
-	if config.viper.Get("test_env") != "test" {
-		t.Errorf("Expected test_env to be test, got %s", config.viper.Get("test_env"))
+	if config.viper.Load().Get("test_env") != "test" {
+		t.Errorf("Expected test_env to be test, got %s", config.viper.Load().Get("test_env"))
 	}
-	if config.viper.Get("test_env_2") != "" {
-		t.Errorf("Expected test_env_2 to be '', got %s", config.viper.Get("test_env_2"))
+	if config.viper.Load().Get("test_env_2") != "" {
+		t.Errorf("Expected test_env_2 to be '', got %s", config.viper.Load().Get("test_env_2"))
 	}
 
-	if config.viper.Get("test_env_3") != nil {
-		t.Errorf("Expected test_env_3 to be nil, got %s", config.viper.Get("test_env_3"))
+	if config.viper.Load().Get("test_env_3") != nil {
+		t.Errorf("Expected test_env_3 to be nil, got %s", config.viper.Load().Get("test_env_3"))
 	}
 }
 
@@ -261,7 +264,11 @@ func TestConfig_Watch(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
-	config.Watch()
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
 
 	assert.Equal(t, "gviper", config.Get("server.name"))
 	assert.Equal(t, nil, config.Get("server.env"))
@@ -314,7 +321,11 @@ func TestConfig_OnChange(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
-	config.Watch()
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
 
 	f1, err := os.OpenFile(serverConfigFile, os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
@@ -357,7 +368,11 @@ func TestConfig_Bind(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
-	config.Watch()
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
 
 	assert.Equal(t, MyServer{
 		Name: "gviper",
@@ -402,7 +417,11 @@ func TestConfig_BindWithTag(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
-	config.Watch()
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
 
 	assert.Equal(t, MyServer{
 		Name: "gviper",
@@ -427,15 +446,24 @@ func TestConfig_BindWithTag(t *testing.T) {
 }
 
 type MyNotification struct {
+	mu         sync.Mutex
 	configName string
 	err        error
 }
 
 func (m *MyNotification) Notify(configName string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.configName = configName
 	m.err = err
 }
 
+func (m *MyNotification) last() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.configName, m.err
+}
+
 func TestConfig_Watch_RegisterNotification(t *testing.T) {
 	d := t.TempDir()
 	t.Logf("tmpdir: %s", d)
@@ -453,18 +481,24 @@ func TestConfig_Watch_RegisterNotification(t *testing.T) {
 	var myServer MyServer
 	myNotification := &MyNotification{}
 
-	config := NewConfig(d)
+	// notifications are delivered on the watch goroutine, like the listeners
+	config := NewConfigWithOptions(WithConfigPath(d), WithNotifyQueue(0, 0))
 	config.Bind("server", &myServer)
 	config.RegisterNotification(myNotification)
 	err = config.Load()
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
-	config.Watch()
-	assert.Equal(t, "", myNotification.configName)
-	assert.Equal(t, nil, myNotification.err)
-	assert.Equal(t, false, errors.Is(myNotification.err, errors.NewWithMessage("read config [%s] error", "server")))
-	assert.Equal(t, false, errors.Is(myNotification.err, errors.NewWithMessage("unmarshal config [%s] failed", "server")))
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+	configName, notifyErr := myNotification.last()
+	assert.Equal(t, "", configName)
+	assert.Equal(t, nil, notifyErr)
+	assert.Equal(t, false, errors.Is(notifyErr, errors.NewWithMessage("read config [%s] error", "server")))
+	assert.Equal(t, false, errors.Is(notifyErr, errors.NewWithMessage("unmarshal config [%s] failed", "server")))
 
 	f2, err := os.OpenFile(serverConfigFile, os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
@@ -477,11 +511,11 @@ func TestConfig_Watch_RegisterNotification(t *testing.T) {
 	_ = f2.Close()
 	time.Sleep(10 * time.Millisecond)
 
-	assert.Equal(t, "server", myNotification.configName)
-	assert.Equal(t, true, errors.Is(myNotification.err, errors.NewWithMessage("unmarshal config [%s] failed", "server")))
+	configName, notifyErr = myNotification.last()
+	assert.Equal(t, "server", configName)
+	assert.Equal(t, true, errors.Is(notifyErr, errors.NewWithMessage("unmarshal config [%s] failed", "server")))
 
-	myNotification.configName = ""
-	myNotification.err = nil
+	myNotification.Notify("", nil)
 
 	f1, err := os.OpenFile(serverConfigFile, os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
@@ -494,8 +528,9 @@ func TestConfig_Watch_RegisterNotification(t *testing.T) {
 	_ = f1.Close()
 	time.Sleep(10 * time.Millisecond)
 
-	assert.Equal(t, "server", myNotification.configName)
-	assert.Equal(t, true, errors.Is(myNotification.err, errors.NewWithMessage("read config [%s] error", "server")))
+	configName, notifyErr = myNotification.last()
+	assert.Equal(t, "server", configName)
+	assert.Equal(t, true, errors.Is(notifyErr, errors.NewWithMessage("read config [%s] error", "server")))
 }
 
 func TestConfig_find(t *testing.T) {
@@ -554,7 +589,7 @@ func TestConfig_viper_method(t *testing.T) {
 		"stringMapStringSlice": map[string][]string{"a": {"b", "c"}, "d": {"e", "f"}},
 		"sizeInBytes":          "1kb",
 	}
-	config.viper.Set("test", m)
+	config.viper.Load().Set("test", m)
 
 	assert.Equal(t, true, config.Has("test.string"))
 	assert.Equal(t, false, config.IsSet("test.string11"))
@@ -576,3 +611,313 @@ func TestConfig_viper_method(t *testing.T) {
 	assert.Equal(t, m["stringMapStringSlice"], config.GetStringMapStringSlice("test.stringMapStringSlice"))
 	assert.Equal(t, uint(1024), config.GetSizeInBytes("test.sizeInBytes"))
 }
+
+func TestConfig_concurrentReads(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper\nport: 8080"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	config := NewConfig(d, "server")
+	config.AutomaticEnv()
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				port := config.GetInt("server.port")
+				assert.True(t, port == 8080 || port == 8081)
+				_ = config.DefaultString("server.name", "")
+				_ = config.AllSettings()
+				_ = config.Sub("server")
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		err = os.WriteFile(serverConfigFile, []byte(fmt.Sprintf("name: gviper\nport: %d", 8080+i%2)), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write server.yaml: %v", err)
+		}
+		err = config.Load()
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestConfig_AutomaticEnv_overrides(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "database.yaml"), []byte("host: localhost\nport: 5432"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.yaml: %v", err)
+	}
+	t.Setenv("APP_DATABASE_PORT", "5433")
+	t.Setenv("APP_DATABASE_USER", "admin")
+	t.Setenv("APP_DATABASE_POOL_MAX_OPEN", "7")
+	t.Setenv("DATABASE_HOST", "ignored")
+
+	type Pool struct {
+		MaxOpen int `json:"max_open"`
+	}
+	type Database struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+		User string `json:"user"`
+		Pool Pool   `json:"pool"`
+	}
+	var database Database
+	var port int
+
+	config := NewConfigWithOptions(WithConfigPath(d), WithAutomaticEnv(), WithEnvPrefix("APP"))
+	config.Bind("database", &database)
+	database2 := BindValue[Database](config, "database")
+	config.OnChange("database", func(v *viper.Viper) error {
+		port = v.GetInt("port")
+		return nil
+	})
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	want := Database{Host: "localhost", Port: 5433, User: "admin", Pool: Pool{MaxOpen: 7}}
+	assert.Equal(t, want, database)
+	assert.Equal(t, &want, database2.Load())
+	assert.Equal(t, 5433, port)
+	assert.Equal(t, 5433, config.GetInt("database.port"))
+	assert.Equal(t, "admin", config.GetString("database.user"))
+	assert.Equal(t, "localhost", config.GetString("database.host"))
+	assert.Equal(t, 5433, config.Sub("database").GetInt("port"))
+}
+
+func TestConfig_AutomaticEnv_withoutPrefix(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "database.yaml"), []byte("host: localhost\nport: 5432"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.yaml: %v", err)
+	}
+	t.Setenv("DATABASE_PORT", "5433")
+
+	type Database struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	var database Database
+
+	config := NewConfig(d)
+	config.AutomaticEnv()
+	config.Bind("database", &database)
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	assert.Equal(t, Database{Host: "localhost", Port: 5433}, database)
+	assert.Equal(t, 5433, config.GetInt("database.port"))
+}
+
+func TestConfig_RegisterOptional(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "app.yaml"), []byte("name: app\nport: 8080"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create app.yaml: %v", err)
+	}
+
+	type Local struct {
+		Port int `json:"port" default:"9090"`
+	}
+	config := NewConfig(d, "app")
+	config.RegisterOptional("local", "override.json")
+	local := BindValue[Local](config, "local")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, 8080, config.GetInt("app.port"))
+	assert.Equal(t, 9090, local.Load().Port)
+	assert.False(t, config.IsSet("override.port"))
+
+	err = os.WriteFile(filepath.Join(d, "local.yaml"), []byte("port: 7070"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create local.yaml: %v", err)
+	}
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, 7070, config.GetInt("local.port"))
+	assert.Equal(t, 7070, local.Load().Port)
+
+	err = os.WriteFile(filepath.Join(d, "override.json"), []byte("{"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create override.json: %v", err)
+	}
+	err = config.Load()
+	assert.True(t, errors.Is(err, errors.NewWithMessage("read config [%s] error", "override")))
+}
+
+func TestConfig_Load_failFast(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "log.yaml"), []byte("level: info"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create log.yaml: %v", err)
+	}
+
+	config := NewConfig(d, "app", "database", "log")
+	err = config.Load()
+	assert.True(t, errors.Is(err, errors.NewWithMessage("read config [%s] error", "app")))
+	assert.False(t, errors.Is(err, errors.NewWithMessage("read config [%s] error", "database")))
+	assert.False(t, config.IsSet("log.level"))
+}
+
+func TestConfig_Load_continue(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "log.yaml"), []byte("level: info"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create log.yaml: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(d, "database.json"), []byte("{"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.json: %v", err)
+	}
+
+	config := NewConfigWithOptions(WithConfigPath(d), WithLoadPolicy(LoadContinue))
+	config.Register("app", "database.json", "log")
+	config.Require("app.name", "log.level")
+	err = config.Load()
+	assert.True(t, errors.Is(err, errors.NewWithMessage("read config [%s] error", "app")))
+	assert.True(t, errors.Is(err, errors.NewWithMessage("read config [%s] error", "database")))
+	assert.True(t, errors.Is(err, ErrKeyNotSet))
+	assert.Contains(t, err.Error(), filepath.Join(d, "app.yaml"))
+	assert.Contains(t, err.Error(), "key [app.name] not set")
+	assert.NotContains(t, err.Error(), "key [log.level] not set")
+	assert.Equal(t, "info", config.GetString("log.level"))
+}
+
+func TestConfig_Reload(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	var notified []string
+	config := NewConfigWithOptions(
+		WithConfigPath(d),
+		WithNotification(notificationFunc(func(configName string, err error) { notified = append(notified, configName) })),
+	)
+	var server struct {
+		Name string `json:"name"`
+	}
+	config.Bind("server", &server)
+	calls := 0
+	config.OnChange("server", func(viper *viper.Viper) error {
+		calls++
+		return nil
+	})
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, 1, calls)
+
+	err = config.Reload("server")
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	assert.Equal(t, 2, calls)
+
+	err = os.WriteFile(serverConfigFile, []byte("name: gviper2"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	err = config.Reload("server.yaml")
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, "gviper2", server.Name)
+	assert.Equal(t, "gviper2", config.GetString("server.name"))
+
+	err = os.WriteFile(serverConfigFile, []byte("name: ["), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	err = config.Reload("server")
+	assert.True(t, errors.Is(err, errors.NewWithMessage("read config [%s] error", "server")))
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, []string{"server"}, notified)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, "gviper2", server.Name)
+
+	err = config.Reload("database")
+	assert.EqualError(t, err, "config [database] not registered")
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, []string{"server"}, notified)
+}
+
+func TestConfig_ReloadAll(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "server.yaml"), []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(d, "log.yaml"), []byte("level: info"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create log.yaml: %v", err)
+	}
+
+	var notified []string
+	config := NewConfigWithOptions(
+		WithConfigPath(d),
+		WithNotification(notificationFunc(func(configName string, err error) { notified = append(notified, configName) })),
+	)
+	config.Register("server", "database", "log")
+	err = config.ReloadAll()
+	assert.True(t, errors.Is(err, errors.NewWithMessage("read config [%s] error", "database")))
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, []string{"database"}, notified)
+	assert.Equal(t, "gviper", config.GetString("server.name"))
+	assert.Equal(t, "info", config.GetString("log.level"))
+
+	err = os.WriteFile(filepath.Join(d, "database.yaml"), []byte("port: 3306"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.yaml: %v", err)
+	}
+	assert.NoError(t, config.ReloadAll())
+	assert.Equal(t, 3306, config.GetInt("database.port"))
+
+	err = os.WriteFile(filepath.Join(d, "app.v2.yaml"), []byte("name: app"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create app.v2.yaml: %v", err)
+	}
+	config.Register("app.v2.yaml")
+	assert.NoError(t, config.ReloadAll())
+	assert.Equal(t, "app", config.GetString("app.v2.name"))
+}
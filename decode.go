@@ -0,0 +1,24 @@
+package gviper
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// StrictDecode makes decoding fail when the config contains keys that match
+// no field of the bound value, e.g. a misspelled `databse`.
+func StrictDecode() viper.DecoderConfigOption {
+	return func(dc *mapstructure.DecoderConfig) {
+		dc.ErrorUnused = true
+	}
+}
+
+// WarnUnusedKeys keeps decoding lenient but reports keys that match no field
+// of the bound value to the registered notifications as an *UnusedKeysError.
+// Every decode gets its own Metadata to collect them.
+func WarnUnusedKeys() viper.DecoderConfigOption {
+	return func(dc *mapstructure.DecoderConfig) {
+		dc.ErrorUnused = false
+		dc.Metadata = &mapstructure.Metadata{}
+	}
+}
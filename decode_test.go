@@ -0,0 +1,194 @@
+package gviper
+
+import (
+	"context"
+	"github.com/ace-zhaoy/errors"
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type decodeDatabase struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	Pool struct {
+		MaxOpen int `json:"max_open"`
+	} `json:"pool"`
+}
+
+func TestWithStrictDecode(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	databaseConfigFile := filepath.Join(d, "database.yaml")
+	err := os.WriteFile(databaseConfigFile, []byte("host: localhost\nport: 5432"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.yaml: %v", err)
+	}
+
+	config := NewConfigWithOptions(WithConfigPath(d), WithStrictDecode())
+	var database decodeDatabase
+	config.Bind("database", &database)
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, 5432, database.Port)
+
+	err = os.WriteFile(databaseConfigFile, []byte("host: localhost\ndatabse:\n  port: 3306\npool:\n  maxopen: 10"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write database.yaml: %v", err)
+	}
+	err = config.Load()
+	assert.True(t, errors.Is(err, errors.NewWithMessage("unmarshal config [%s] failed", "database")))
+	assert.Contains(t, err.Error(), "databse")
+	assert.Contains(t, err.Error(), "maxopen")
+	assert.Equal(t, 5432, database.Port)
+}
+
+func TestStrictDecode_perBind(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "database.yaml"), []byte("host: localhost\ndatabse: 1"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.yaml: %v", err)
+	}
+
+	config := NewConfig(d)
+	var database decodeDatabase
+	config.BindWithTag("database", &database, "json", StrictDecode())
+	err = config.Load()
+	assert.Contains(t, err.Error(), "databse")
+
+	config = NewConfigWithOptions(WithConfigPath(d), WithStrictDecode())
+	config.BindWithTag("database", &database, "json", WarnUnusedKeys())
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, "localhost", database.Host)
+}
+
+func TestWithWarnUnusedKeys(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	databaseConfigFile := filepath.Join(d, "database.yaml")
+	err := os.WriteFile(databaseConfigFile, []byte("host: localhost\ndatabse:\n  port: 3306\npool:\n  maxopen: 10"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.yaml: %v", err)
+	}
+
+	var notified []error
+	config := NewConfigWithOptions(
+		WithConfigPath(d),
+		WithWarnUnusedKeys(),
+		WithNotification(notificationFunc(func(configName string, err error) {
+			assert.Equal(t, "database", configName)
+			notified = append(notified, err)
+		})),
+	)
+	var database decodeDatabase
+	config.Bind("database", &database)
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, "localhost", database.Host)
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, 1, len(notified))
+	assert.True(t, errors.Is(notified[0], ErrUnusedKeys))
+	unusedKeysError, ok := notified[0].(*UnusedKeysError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"database.databse", "database.pool.maxopen"}, unusedKeysError.Keys)
+	assert.Equal(t, databaseConfigFile, unusedKeysError.File)
+
+	err = os.WriteFile(databaseConfigFile, []byte("host: localhost\nport: 3306"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write database.yaml: %v", err)
+	}
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, 1, len(notified))
+}
+
+func TestWarnUnusedKeys_sharedConfig(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "app.yaml"), []byte("name: app\nport: 8080\nnmae: typo"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create app.yaml: %v", err)
+	}
+
+	var notified []error
+	config := NewConfigWithOptions(
+		WithConfigPath(d),
+		WithWarnUnusedKeys(),
+		WithNotification(notificationFunc(func(configName string, err error) { notified = append(notified, err) })),
+	)
+	var name struct {
+		Name string `json:"name"`
+	}
+	var port struct {
+		Port int `json:"port"`
+	}
+	config.Bind("app", &name)
+	config.Bind("app", &port)
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, 1, len(notified))
+	assert.Equal(t, []string{"app.nmae"}, notified[0].(*UnusedKeysError).Keys)
+}
+
+func TestWithWarnUnusedKeys_Get(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "database.yaml"), []byte("host: localhost\nport: 5432\ndatabse: 1"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.yaml: %v", err)
+	}
+
+	var mu sync.Mutex
+	var collected []*mapstructure.Metadata
+	config := NewConfigWithOptions(
+		WithConfigPath(d),
+		WithWarnUnusedKeys(),
+		WithDecoderConfigOptions(func(dc *mapstructure.DecoderConfig) {
+			mu.Lock()
+			defer mu.Unlock()
+			collected = append(collected, dc.Metadata)
+		}),
+	)
+	config.Register("database")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			database, err := Get[struct {
+				Host string `json:"host"`
+			}](config, "database")
+			assert.NoError(t, err)
+			assert.Equal(t, "localhost", database.Host)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 20, len(collected))
+	for _, md := range collected {
+		assert.Equal(t, 0, len(md.Keys))
+		assert.Equal(t, 0, len(md.Unused))
+	}
+	assert.NotSame(t, collected[0], collected[1])
+}
@@ -0,0 +1,175 @@
+package gviper
+
+import (
+	"context"
+	"github.com/ace-zhaoy/go-utils/usafe"
+	"sync"
+)
+
+// OverflowPolicy decides what happens to a notification when the dispatch
+// queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest drops the notification being dispatched.
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest queued notification to make room.
+	OverflowDropOldest
+	// OverflowBlock waits for room, which blocks the reload that dispatches.
+	OverflowBlock
+)
+
+// dispatcher delivers notifications on background workers, so a slow notifier
+// never holds up a reload. Workers are started as notifications are queued and
+// exit once the queue is empty, so an idle dispatcher holds no goroutines.
+type dispatcher struct {
+	mu      sync.Mutex
+	room    *sync.Cond
+	queue   []*NotifyEvent
+	size    int
+	workers int
+	running int
+	policy  OverflowPolicy
+	deliver func(event *NotifyEvent)
+	pending int
+	idle    chan struct{}
+	closed  bool
+}
+
+func newDispatcher(size int, workers int, policy OverflowPolicy, deliver func(event *NotifyEvent)) *dispatcher {
+	if size < 1 {
+		size = 1
+	}
+	d := &dispatcher{
+		size:    size,
+		workers: workers,
+		policy:  policy,
+		deliver: deliver,
+		idle:    make(chan struct{}),
+	}
+	d.room = sync.NewCond(&d.mu)
+	close(d.idle)
+	return d
+}
+
+// enqueue queues event according to the overflow policy. It returns false
+// once the dispatcher is closed.
+func (d *dispatcher) enqueue(event *NotifyEvent) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for !d.closed && len(d.queue) >= d.size {
+		switch d.policy {
+		case OverflowBlock:
+			d.room.Wait()
+		case OverflowDropOldest:
+			d.queue = d.queue[1:]
+			d.done()
+		default:
+			return true
+		}
+	}
+	if d.closed {
+		return false
+	}
+	if d.pending == 0 {
+		d.idle = make(chan struct{})
+	}
+	d.pending++
+	d.queue = append(d.queue, event)
+	if d.running < d.workers {
+		d.running++
+		go d.work()
+	}
+	return true
+}
+
+func (d *dispatcher) work() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for !d.closed && len(d.queue) > 0 {
+		event := d.queue[0]
+		d.queue = d.queue[1:]
+		d.room.Broadcast()
+		d.mu.Unlock()
+		_ = usafe.Func(func() { d.deliver(event) })
+		d.mu.Lock()
+		d.done()
+	}
+	d.running--
+}
+
+// done marks a queued notification as delivered or dropped, and closes idle
+// once none is left so flush can return. Callers hold d.mu.
+func (d *dispatcher) done() {
+	d.pending--
+	if d.pending == 0 {
+		close(d.idle)
+	}
+}
+
+func (d *dispatcher) flush(ctx context.Context) error {
+	d.mu.Lock()
+	idle := d.idle
+	d.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close discards the queued notifications. A delivery in progress is not
+// waited for, so a notifier may call Close.
+func (d *dispatcher) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	for range d.queue {
+		d.done()
+	}
+	d.queue = nil
+	d.room.Broadcast()
+}
+
+// Flush waits until every notification dispatched so far has been delivered,
+// or ctx is done. Call it before Close on shutdown, as Close discards queued
+// notifications.
+func (c *Config) Flush(ctx context.Context) error {
+	c.dispatchMu.Lock()
+	d := c.dispatcher
+	c.dispatchMu.Unlock()
+	if d == nil {
+		return nil
+	}
+	return d.flush(ctx)
+}
+
+// dispatch queues event for the background workers. Without workers, or once
+// Close has stopped the dispatcher, it is delivered synchronously.
+func (c *Config) dispatch(event *NotifyEvent) {
+	if c.notifyWorkers <= 0 {
+		c.deliver(event)
+		return
+	}
+	c.dispatchMu.Lock()
+	if c.dispatcher == nil {
+		c.dispatcher = newDispatcher(c.notifyQueueSize, c.notifyWorkers, c.notifyOverflow, c.deliver)
+	}
+	d := c.dispatcher
+	c.dispatchMu.Unlock()
+	if !d.enqueue(event) {
+		c.deliver(event)
+	}
+}
+
+func (c *Config) closeDispatcher() {
+	c.dispatchMu.Lock()
+	d := c.dispatcher
+	if d == nil {
+		d = newDispatcher(c.notifyQueueSize, c.notifyWorkers, c.notifyOverflow, c.deliver)
+		c.dispatcher = d
+	}
+	c.dispatchMu.Unlock()
+	d.close()
+}
@@ -0,0 +1,180 @@
+package gviper
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type blockingNotifier struct {
+	mu      sync.Mutex
+	release chan struct{}
+	started chan struct{}
+	kinds   []NotifyKind
+	errs    []string
+}
+
+func newBlockingNotifier() *blockingNotifier {
+	return &blockingNotifier{release: make(chan struct{}), started: make(chan struct{}, 10)}
+}
+
+func (b *blockingNotifier) NotifyEvent(event *NotifyEvent) {
+	b.started <- struct{}{}
+	<-b.release
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.kinds = append(b.kinds, event.Kind)
+	b.errs = append(b.errs, event.ConfigName)
+}
+
+func (b *blockingNotifier) names() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.errs...)
+}
+
+func TestConfig_Flush(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "server.yaml"), []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	notifier := newBlockingNotifier()
+	config := NewConfigWithOptions(WithConfigPath(d), WithEventNotifier(notifier))
+	config.Register("server")
+	assert.NoError(t, config.Flush(context.Background()))
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			_ = config.Reload("server")
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected reload not to wait for the notifier")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, config.Flush(ctx))
+	close(notifier.release)
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, []NotifyKind{NotifyReloadSucceeded, NotifyReloadSucceeded, NotifyReloadSucceeded}, notifier.kinds)
+	assert.NoError(t, config.Close())
+}
+
+func TestConfig_Close_discardsQueued(t *testing.T) {
+	notifier := newBlockingNotifier()
+	config := NewConfigWithOptions(WithEventNotifier(notifier))
+	config.dispatch(&NotifyEvent{ConfigName: "a"})
+	<-notifier.started
+	config.dispatch(&NotifyEvent{ConfigName: "b"})
+
+	assert.NoError(t, config.Close())
+	close(notifier.release)
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, []string{"a"}, notifier.names())
+
+	config.dispatch(&NotifyEvent{ConfigName: "c"})
+	<-notifier.started
+	assert.Equal(t, []string{"a", "c"}, notifier.names())
+}
+
+func TestDispatcher_idle(t *testing.T) {
+	var mu sync.Mutex
+	var names []string
+	d := newDispatcher(10, 2, OverflowDropNewest, func(event *NotifyEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		names = append(names, event.ConfigName)
+	})
+	defer d.close()
+	for _, name := range []string{"a", "b", "c"} {
+		d.enqueue(&NotifyEvent{ConfigName: name})
+	}
+	assert.NoError(t, d.flush(context.Background()))
+	assert.Eventually(t, func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.running == 0
+	}, time.Second, time.Millisecond, "Expected idle workers to exit")
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, names)
+
+	d.enqueue(&NotifyEvent{ConfigName: "d"})
+	assert.NoError(t, d.flush(context.Background()))
+	assert.Len(t, names, 4)
+}
+
+func TestWithNotifyQueue_sync(t *testing.T) {
+	var names []string
+	config := NewConfigWithOptions(
+		WithNotifyQueue(0, 0),
+		WithEventNotifier(eventNotifierFunc(func(event *NotifyEvent) { names = append(names, event.ConfigName) })),
+	)
+	config.dispatch(&NotifyEvent{ConfigName: "a"})
+	assert.Equal(t, []string{"a"}, names)
+	assert.Nil(t, config.dispatcher)
+}
+
+func TestDispatcher_overflow(t *testing.T) {
+	cases := []struct {
+		policy OverflowPolicy
+		want   []string
+	}{
+		{OverflowDropNewest, []string{"a", "b"}},
+		{OverflowDropOldest, []string{"a", "c"}},
+		{OverflowBlock, []string{"a", "b", "c"}},
+	}
+	for _, tc := range cases {
+		notifier := newBlockingNotifier()
+		d := newDispatcher(1, 1, tc.policy, notifier.NotifyEvent)
+		d.enqueue(&NotifyEvent{ConfigName: "a"})
+		<-notifier.started
+		d.enqueue(&NotifyEvent{ConfigName: "b"})
+
+		enqueued := make(chan struct{})
+		go func() {
+			defer close(enqueued)
+			d.enqueue(&NotifyEvent{ConfigName: "c"})
+		}()
+		select {
+		case <-enqueued:
+			assert.NotEqual(t, OverflowBlock, tc.policy, "Expected enqueue to block while the queue is full")
+		case <-time.After(10 * time.Millisecond):
+			assert.Equal(t, OverflowBlock, tc.policy, "Expected enqueue not to block")
+		}
+		close(notifier.release)
+		<-enqueued
+		assert.NoError(t, d.flush(context.Background()))
+		assert.Equal(t, tc.want, notifier.names(), "policy %d", tc.policy)
+		d.close()
+	}
+}
+
+func TestDispatcher_panic(t *testing.T) {
+	var names []string
+	d := newDispatcher(10, 1, OverflowDropNewest, func(event *NotifyEvent) {
+		if event.ConfigName == "a" {
+			panic("boom")
+		}
+		names = append(names, event.ConfigName)
+	})
+	defer d.close()
+	d.enqueue(&NotifyEvent{ConfigName: "a"})
+	d.enqueue(&NotifyEvent{ConfigName: "b"})
+	assert.NoError(t, d.flush(context.Background()))
+	assert.Equal(t, []string{"b"}, names)
+}
@@ -0,0 +1,93 @@
+package gviper
+
+import (
+	"fmt"
+	"github.com/ace-zhaoy/errors"
+	"github.com/ace-zhaoy/go-utils/uslice"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrKeyNotSet  = errors.New("key not set")
+	ErrConvert    = errors.New("value not convertible")
+	ErrValidation = errors.New("validation failed")
+	ErrUnusedKeys = errors.New("unused keys")
+)
+
+type KeyNotSetError struct {
+	Key  string
+	File string
+}
+
+func (e *KeyNotSetError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("key [%s] not set", e.Key)
+	}
+	return fmt.Sprintf("key [%s] not set in config file [%s]", e.Key, e.File)
+}
+
+func (e *KeyNotSetError) Is(target error) bool {
+	return target == ErrKeyNotSet
+}
+
+type ConvertError struct {
+	Key   string
+	File  string
+	Value any
+	Type  string
+	Err   error
+}
+
+func (e *ConvertError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("key [%s] value %#v cannot be converted to %s: %v", e.Key, e.Value, e.Type, e.Err)
+	}
+	return fmt.Sprintf("key [%s] value %#v in config file [%s] cannot be converted to %s: %v", e.Key, e.Value, e.File, e.Type, e.Err)
+}
+
+func (e *ConvertError) Is(target error) bool {
+	return target == ErrConvert
+}
+
+func (e *ConvertError) Unwrap() error {
+	return e.Err
+}
+
+type ValidationError struct {
+	Key  string
+	File string
+	Rule string
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("key [%s] in config file [%s] failed rule [%s]: %v", e.Key, e.File, e.Rule, e.Err)
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+type UnusedKeysError struct {
+	Keys []string
+	File string
+}
+
+func newUnusedKeysError(cp *configParam, keys []string) *UnusedKeysError {
+	keys = uslice.Map(uslice.Unique(keys), func(key string) string { return joinKey(cp.configName, key) })
+	sort.Strings(keys)
+	return &UnusedKeysError{Keys: keys, File: cp.configFile}
+}
+
+func (e *UnusedKeysError) Error() string {
+	return fmt.Sprintf("unused keys [%s] in config file [%s]", strings.Join(e.Keys, ", "), e.File)
+}
+
+func (e *UnusedKeysError) Is(target error) bool {
+	return target == ErrUnusedKeys
+}
@@ -0,0 +1,135 @@
+package gviper
+
+import (
+	"github.com/spf13/viper"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type ChangeKind int
+
+const (
+	// ChangeUpdated is sent when the file was loaded.
+	ChangeUpdated ChangeKind = iota
+	// ChangeRemoved is sent when a watched file was deleted. Current holds
+	// the last values under RemoveKeep and the reset values under RemoveReset.
+	ChangeRemoved
+)
+
+// ChangeEvent describes a load of a single config. Previous and Current are
+// the settings before and after the load and must not be modified. Added,
+// Removed and Modified list the dotted leaf keys that differ, relative to the
+// config, e.g. "pool.size" for "database.pool.size".
+type ChangeEvent struct {
+	Kind       ChangeKind
+	ConfigName string
+	Previous   map[string]any
+	Current    map[string]any
+	Added      []string
+	Removed    []string
+	Modified   []string
+	viper      *viper.Viper
+}
+
+type EventListener func(event *ChangeEvent) error
+
+func newChangeEvent(configName string, previous, current map[string]any, v *viper.Viper) *ChangeEvent {
+	if previous == nil {
+		previous = map[string]any{}
+	}
+	e := &ChangeEvent{
+		ConfigName: configName,
+		Previous:   previous,
+		Current:    current,
+		Added:      []string{},
+		Removed:    []string{},
+		Modified:   []string{},
+		viper:      v,
+	}
+	prev, curr := flatten(previous), flatten(current)
+	for k, cv := range curr {
+		pv, ok := prev[k]
+		switch {
+		case !ok:
+			e.Added = append(e.Added, k)
+		case !reflect.DeepEqual(pv, cv):
+			e.Modified = append(e.Modified, k)
+		}
+	}
+	for k := range prev {
+		if _, ok := curr[k]; !ok {
+			e.Removed = append(e.Removed, k)
+		}
+	}
+	sort.Strings(e.Added)
+	sort.Strings(e.Removed)
+	sort.Strings(e.Modified)
+	return e
+}
+
+func (e *ChangeEvent) Viper() *viper.Viper {
+	return e.viper
+}
+
+// ChangedKeys returns every added, removed and modified key in sorted order.
+func (e *ChangeEvent) ChangedKeys() []string {
+	keys := make([]string, 0, len(e.Added)+len(e.Removed)+len(e.Modified))
+	keys = append(keys, e.Added...)
+	keys = append(keys, e.Removed...)
+	keys = append(keys, e.Modified...)
+	sort.Strings(keys)
+	return keys
+}
+
+func (e *ChangeEvent) HasChanges() bool {
+	return len(e.Added)+len(e.Removed)+len(e.Modified) > 0
+}
+
+// Changed reports whether the value at pattern differs between Previous and
+// Current. A "*" segment matches any single key segment, and a pattern also
+// matches changes below it, so "replicas.*" matches "replicas.r1.host".
+func (e *ChangeEvent) Changed(pattern string) bool {
+	if pattern == "" {
+		return e.HasChanges()
+	}
+	segments := strings.Split(strings.ToLower(pattern), ".")
+	for _, key := range e.ChangedKeys() {
+		if matchKey(segments, strings.Split(key, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchKey(pattern, key []string) bool {
+	if len(key) < len(pattern) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != key[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func flatten(settings map[string]any) map[string]any {
+	m := make(map[string]any)
+	flattenInto(m, "", settings)
+	return m
+}
+
+func flattenInto(m map[string]any, prefix string, settings map[string]any) {
+	for k, v := range settings {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if sub, ok := v.(map[string]any); ok && len(sub) > 0 {
+			flattenInto(m, key, sub)
+			continue
+		}
+		m[key] = v
+	}
+}
@@ -0,0 +1,102 @@
+package gviper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewChangeEvent(t *testing.T) {
+	previous := map[string]any{
+		"name": "gviper",
+		"env":  "dev",
+		"pool": map[string]any{"size": 10, "idle": 2},
+		"tags": []any{"a"},
+	}
+	current := map[string]any{
+		"name": "gviper",
+		"pool": map[string]any{"size": 20, "idle": 2, "max": 30},
+		"tags": []any{"a", "b"},
+		"http": map[string]any{"port": 8080},
+	}
+	e := newChangeEvent("server", previous, current, nil)
+	assert.Equal(t, "server", e.ConfigName)
+	assert.Equal(t, []string{"http.port", "pool.max"}, e.Added)
+	assert.Equal(t, []string{"env"}, e.Removed)
+	assert.Equal(t, []string{"pool.size", "tags"}, e.Modified)
+	assert.Equal(t, []string{"env", "http.port", "pool.max", "pool.size", "tags"}, e.ChangedKeys())
+	assert.True(t, e.HasChanges())
+
+	e = newChangeEvent("server", current, current, nil)
+	assert.Equal(t, []string{}, e.ChangedKeys())
+	assert.False(t, e.HasChanges())
+
+	e = newChangeEvent("server", nil, map[string]any{"name": "gviper"}, nil)
+	assert.Equal(t, map[string]any{}, e.Previous)
+	assert.Equal(t, []string{"name"}, e.Added)
+}
+
+func TestConfig_OnChangeEvent(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper\nenv: dev"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	var events []*ChangeEvent
+	config := NewConfig(d)
+	config.OnChangeEvent("server", func(event *ChangeEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	err = os.WriteFile(serverConfigFile, []byte("name: gviper2\nport: 8080"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, []string{"env", "name"}, events[0].Added)
+	assert.Equal(t, map[string]any{"name": "gviper", "env": "dev"}, events[1].Previous)
+	assert.Equal(t, map[string]any{"name": "gviper2", "port": 8080}, events[1].Current)
+	assert.Equal(t, []string{"port"}, events[1].Added)
+	assert.Equal(t, []string{"env"}, events[1].Removed)
+	assert.Equal(t, []string{"name"}, events[1].Modified)
+	assert.Equal(t, "gviper2", events[1].Viper().GetString("name"))
+}
+
+func TestChangeEvent_Changed(t *testing.T) {
+	previous := map[string]any{
+		"pool":     map[string]any{"size": 10, "idle": 2},
+		"replicas": []any{"a"},
+		"shards":   map[string]any{"s1": map[string]any{"host": "a"}, "s2": map[string]any{"host": "b"}},
+	}
+	current := map[string]any{
+		"pool":     map[string]any{"size": 20, "idle": 2},
+		"replicas": []any{"a"},
+		"shards":   map[string]any{"s1": map[string]any{"host": "a"}, "s2": map[string]any{"host": "c"}},
+	}
+	e := newChangeEvent("database", previous, current, nil)
+	assert.True(t, e.Changed(""))
+	assert.True(t, e.Changed("pool"))
+	assert.True(t, e.Changed("pool.size"))
+	assert.True(t, e.Changed("Pool.Size"))
+	assert.False(t, e.Changed("pool.idle"))
+	assert.False(t, e.Changed("replicas"))
+	assert.True(t, e.Changed("shards.*"))
+	assert.True(t, e.Changed("shards.*.host"))
+	assert.False(t, e.Changed("shards.s1.*"))
+	assert.True(t, e.Changed("shards.s2.host"))
+	assert.False(t, e.Changed("pool.size.max"))
+}
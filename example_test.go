@@ -1,6 +1,7 @@
 package gviper_test
 
 import (
+	"context"
 	"github.com/ace-zhaoy/gviper"
 	"github.com/ace-zhaoy/gviper/notifications"
 	"github.com/mitchellh/mapstructure"
@@ -16,7 +17,7 @@ func ExampleNewConfig() {
 	}
 
 	// 监听配置文件变化（非阻塞）
-	config.Watch()
+	_ = config.Watch(context.Background())
 
 	_ = config.GetString("app.name")
 	_ = config.GetInt("database.port")
@@ -34,7 +35,7 @@ func ExampleConfig_Register() {
 	}
 
 	// 监听配置文件变化（非阻塞）
-	config.Watch()
+	_ = config.Watch(context.Background())
 
 	_ = config.GetString("app.name")
 	_ = config.GetInt("database.port")
@@ -60,7 +61,7 @@ func ExampleConfig_Bind() {
 	config.Bind("app", &ac)
 
 	_ = config.Load()
-	config.Watch()
+	_ = config.Watch(context.Background())
 
 	_ = lc.Level
 	_ = ac.Name
@@ -85,7 +86,7 @@ func ExampleConfig_Bind_register() {
 	config.Bind("app", &ac)
 
 	_ = config.Load()
-	config.Watch()
+	_ = config.Watch(context.Background())
 
 	_ = lc.Level
 	_ = ac.Name
@@ -121,12 +122,28 @@ func ExampleConfig_BindWithTag() {
 	})
 
 	_ = config.Load()
-	config.Watch()
+	_ = config.Watch(context.Background())
 
 	_ = lc.Level
 	_ = ac.Name
 }
 
+func ExampleBindValue() {
+	type LogConfig struct {
+		Type  string `json:"type"`
+		Level string `json:"level"`
+	}
+
+	config := gviper.NewConfig(".", "log.toml")
+
+	lc := gviper.BindValue[LogConfig](config, "log")
+
+	_ = config.Load()
+	_ = config.Watch(context.Background())
+
+	_ = lc.Load().Level
+}
+
 func ExampleConfig_RegisterNotification() {
 	config := gviper.NewConfig(".", "app", "database.json", "log.toml")
 	config.RegisterNotification(
@@ -134,7 +151,7 @@ func ExampleConfig_RegisterNotification() {
 	)
 
 	_ = config.Load()
-	config.Watch()
+	_ = config.Watch(context.Background())
 }
 
 func ExampleConfig_Load() {
@@ -147,5 +164,9 @@ func ExampleConfig_Watch() {
 	config := gviper.NewConfig(".", "app", "database.json", "log.toml")
 
 	_ = config.Load()
-	config.Watch()
+	if err := config.Watch(context.Background()); err != nil {
+		panic(err)
+	}
+	// 停止监听
+	defer config.Close()
 }
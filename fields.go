@@ -0,0 +1,86 @@
+package gviper
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// walkFields calls fn for every leaf field of struct type t with the dotted,
+// lower-cased key mapstructure would decode it from. Nested structs and
+// pointers to structs are descended into, squashed structs share their
+// parent's prefix, and time.Time or encoding.TextUnmarshaler fields are leaves.
+func walkFields(t reflect.Type, tagName string, prefix string, fn func(path string, f reflect.StructField)) {
+	walkFieldsSeen(t, tagName, prefix, fn, map[reflect.Type]bool{})
+}
+
+func walkFieldsSeen(t reflect.Type, tagName string, prefix string, fn func(path string, f reflect.StructField), seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, squash, skip := fieldKey(f, tagName)
+		if skip {
+			continue
+		}
+		path := prefix
+		if !squash {
+			path = joinKey(prefix, name)
+		}
+		if isStructField(f.Type) {
+			walkFieldsSeen(f.Type, tagName, path, fn, seen)
+			continue
+		}
+		fn(path, f)
+	}
+}
+
+func fieldKey(f reflect.StructField, tagName string) (name string, squash bool, skip bool) {
+	tag := f.Tag.Get(tagName)
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "squash" {
+			squash = true
+		}
+	}
+	name = parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	return strings.ToLower(name), squash, false
+}
+
+func isStructField(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct &&
+		t != timeType &&
+		!reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,45 @@
+package gviper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWalkFields(t *testing.T) {
+	type Base struct {
+		ID string `json:"id"`
+	}
+	type Pool struct {
+		MaxOpen int `json:"max_open"`
+	}
+	type Node struct {
+		Name string `json:"name"`
+		Next *Node  `json:"next"`
+	}
+	type Database struct {
+		Base     `json:",squash"`
+		Host     string            `json:"host"`
+		Port     int               `json:"port,omitempty"`
+		Pool     Pool              `json:"pool"`
+		Replica  *Pool             `json:"replica"`
+		Date     time.Time         `json:"date"`
+		IP       net.IP            `json:"ip"`
+		Tags     map[string]string `json:"tags"`
+		Ignored  string            `json:"-"`
+		NoTag    string
+		Node     Node `json:"node"`
+		internal string
+	}
+
+	var paths []string
+	walkFields(reflect.TypeOf(&Database{}), "json", "", func(path string, f reflect.StructField) {
+		paths = append(paths, path)
+	})
+	assert.Equal(t, []string{
+		"id", "host", "port", "pool.max_open", "replica.max_open",
+		"date", "ip", "tags", "notag", "node.name",
+	}, paths)
+}
@@ -0,0 +1,191 @@
+package gviper
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Get decodes the value at key into T with the same decoder settings Bind
+// uses, so custom types such as []ServerAddr or net.IP can be read directly.
+func Get[T any](c *Config, key string) (T, error) {
+	opts := append([]viper.DecoderConfigOption(nil), c.decoderConfigOptions...)
+	opts = append(opts, func(dc *mapstructure.DecoderConfig) { dc.TagName = "json" })
+	return getE(c, key, func(value any) (T, error) {
+		var result T
+		err := decode(value, &result, opts...)
+		return result, err
+	})
+}
+
+// GetOr returns defaultValue when key is not set or cannot be decoded into T.
+func GetOr[T any](c *Config, key string, defaultValue T) T {
+	value, err := Get[T](c, key)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// decode mirrors viper's default decoder config used by Unmarshal.
+func decode(input any, output any, decoderConfigOptions ...viper.DecoderConfigOption) error {
+	dc := &mapstructure.DecoderConfig{
+		Result:           output,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	}
+	for _, opt := range decoderConfigOptions {
+		opt(dc)
+	}
+	// unused keys are only reported for bindings
+	dc.Metadata = nil
+	decoder, err := mapstructure.NewDecoder(dc)
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
+}
+
+// getE returns a *KeyNotSetError when key is not set and a *ConvertError when
+// its value cannot be converted to T.
+func getE[T any](c *Config, key string, convert func(value any) (T, error)) (T, error) {
+	var zero T
+	v := c.viper.Load()
+	if !v.IsSet(key) {
+		return zero, &KeyNotSetError{Key: key, File: c.fileOf(key)}
+	}
+	value := v.Get(key)
+	result, err := convert(value)
+	if err != nil {
+		return zero, &ConvertError{
+			Key:   key,
+			File:  c.fileOf(key),
+			Value: value,
+			Type:  reflect.TypeOf(&zero).Elem().String(),
+			Err:   err,
+		}
+	}
+	return result, nil
+}
+
+func (c *Config) fileOf(key string) string {
+	if cp := c.findByKey(key); cp != nil {
+		return cp.configFile
+	}
+	return ""
+}
+
+func (c *Config) GetE(key string) (any, error) {
+	return getE(c, key, func(value any) (any, error) { return value, nil })
+}
+
+func (c *Config) GetStringE(key string) (string, error) {
+	return getE(c, key, cast.ToStringE)
+}
+
+func (c *Config) GetBoolE(key string) (bool, error) {
+	return getE(c, key, cast.ToBoolE)
+}
+
+func (c *Config) GetIntE(key string) (int, error) {
+	return getE(c, key, cast.ToIntE)
+}
+
+func (c *Config) GetInt32E(key string) (int32, error) {
+	return getE(c, key, cast.ToInt32E)
+}
+
+func (c *Config) GetInt64E(key string) (int64, error) {
+	return getE(c, key, cast.ToInt64E)
+}
+
+func (c *Config) GetUintE(key string) (uint, error) {
+	return getE(c, key, cast.ToUintE)
+}
+
+func (c *Config) GetUint32E(key string) (uint32, error) {
+	return getE(c, key, cast.ToUint32E)
+}
+
+func (c *Config) GetUint64E(key string) (uint64, error) {
+	return getE(c, key, cast.ToUint64E)
+}
+
+func (c *Config) GetFloat64E(key string) (float64, error) {
+	return getE(c, key, cast.ToFloat64E)
+}
+
+func (c *Config) GetTimeE(key string) (time.Time, error) {
+	return getE(c, key, cast.ToTimeE)
+}
+
+func (c *Config) GetDurationE(key string) (time.Duration, error) {
+	return getE(c, key, cast.ToDurationE)
+}
+
+func (c *Config) GetIntSliceE(key string) ([]int, error) {
+	return getE(c, key, cast.ToIntSliceE)
+}
+
+func (c *Config) GetStringSliceE(key string) ([]string, error) {
+	return getE(c, key, cast.ToStringSliceE)
+}
+
+func (c *Config) GetStringMapE(key string) (map[string]any, error) {
+	return getE(c, key, cast.ToStringMapE)
+}
+
+func (c *Config) GetStringMapStringE(key string) (map[string]string, error) {
+	return getE(c, key, cast.ToStringMapStringE)
+}
+
+func (c *Config) GetStringMapStringSliceE(key string) (map[string][]string, error) {
+	return getE(c, key, cast.ToStringMapStringSliceE)
+}
+
+func (c *Config) GetSizeInBytesE(key string) (uint, error) {
+	return getE(c, key, func(value any) (uint, error) {
+		s, err := cast.ToStringE(value)
+		if err != nil {
+			return 0, err
+		}
+		return parseSizeInBytes(s)
+	})
+}
+
+// parseSizeInBytes mirrors viper's size parsing but reports invalid sizes
+// instead of returning 0.
+func parseSizeInBytes(sizeStr string) (uint, error) {
+	sizeStr = strings.TrimSpace(sizeStr)
+	lastChar := len(sizeStr) - 1
+	multiplier := uint(1)
+
+	if lastChar > 1 && (sizeStr[lastChar] == 'b' || sizeStr[lastChar] == 'B') {
+		switch unicode.ToLower(rune(sizeStr[lastChar-1])) {
+		case 'k':
+			multiplier = 1 << 10
+			sizeStr = strings.TrimSpace(sizeStr[:lastChar-1])
+		case 'm':
+			multiplier = 1 << 20
+			sizeStr = strings.TrimSpace(sizeStr[:lastChar-1])
+		case 'g':
+			multiplier = 1 << 30
+			sizeStr = strings.TrimSpace(sizeStr[:lastChar-1])
+		default:
+			sizeStr = strings.TrimSpace(sizeStr[:lastChar])
+		}
+	}
+
+	size, err := cast.ToUintE(sizeStr)
+	if err != nil {
+		return 0, err
+	}
+	return size * multiplier, nil
+}
@@ -0,0 +1,169 @@
+package gviper
+
+import (
+	stderrors "errors"
+	"github.com/ace-zhaoy/errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type serverAddr struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+func TestGet(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "server.yaml"), []byte(`
+name: gviper
+port: "8080"
+bad_port: abc
+timeout: 3s
+ip: 10.0.0.1
+date: 2021-11-17T16:25:15+08:00
+addrs:
+  - host: a
+    port: 1
+  - host: b
+    port: 2
+`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	config := Default(d)
+	config.Register("server")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	name, err := Get[string](config, "server.name")
+	assert.NoError(t, err)
+	assert.Equal(t, "gviper", name)
+
+	port, err := Get[int](config, "server.port")
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, port)
+
+	timeout, err := Get[time.Duration](config, "server.timeout")
+	assert.NoError(t, err)
+	assert.Equal(t, 3*time.Second, timeout)
+
+	ip, err := Get[net.IP](config, "server.ip")
+	assert.NoError(t, err)
+	assert.Equal(t, net.ParseIP("10.0.0.1"), ip)
+
+	date, err := Get[time.Time](config, "server.date")
+	assert.NoError(t, err)
+	td, _ := time.Parse(time.RFC3339, "2021-11-17T16:25:15+08:00")
+	assert.Equal(t, td, date)
+
+	addrs, err := Get[[]serverAddr](config, "server.addrs")
+	assert.NoError(t, err)
+	assert.Equal(t, []serverAddr{{Host: "a", Port: 1}, {Host: "b", Port: 2}}, addrs)
+
+	_, err = Get[int](config, "server.bad_port")
+	assert.Error(t, err)
+
+	_, err = Get[int](config, "server.missing")
+	assert.Error(t, err)
+}
+
+func TestGetOr(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "server.yaml"), []byte("port: 8080\nbad_port: abc"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	config := NewConfig(d, "server")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	assert.Equal(t, 8080, GetOr(config, "server.port", 80))
+	assert.Equal(t, 80, GetOr(config, "server.bad_port", 80))
+	assert.Equal(t, 80, GetOr(config, "server.missing", 80))
+	assert.Equal(t, []string{"a"}, GetOr(config, "server.tags", []string{"a"}))
+}
+
+func TestConfig_GetE(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte(`
+name: gviper
+port: abc
+debug: true
+timeout: 3s
+size: 2kb
+bad_size: 2xb
+tags: [a, b]
+`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	config := NewConfig(d, "server")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	name, err := config.GetStringE("server.name")
+	assert.NoError(t, err)
+	assert.Equal(t, "gviper", name)
+
+	debug, err := config.GetBoolE("server.debug")
+	assert.NoError(t, err)
+	assert.Equal(t, true, debug)
+
+	timeout, err := config.GetDurationE("server.timeout")
+	assert.NoError(t, err)
+	assert.Equal(t, 3*time.Second, timeout)
+
+	size, err := config.GetSizeInBytesE("server.size")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2048), size)
+
+	tags, err := config.GetStringSliceE("server.tags")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, tags)
+
+	_, err = config.GetIntE("server.port")
+	assert.True(t, errors.Is(err, ErrConvert))
+	assert.False(t, errors.Is(err, ErrKeyNotSet))
+	var convertErr *ConvertError
+	assert.True(t, stderrors.As(err, &convertErr))
+	assert.Equal(t, "server.port", convertErr.Key)
+	assert.Equal(t, serverConfigFile, convertErr.File)
+	assert.Equal(t, "int", convertErr.Type)
+	assert.Contains(t, err.Error(), "server.port")
+	assert.Contains(t, err.Error(), serverConfigFile)
+
+	_, err = config.GetSizeInBytesE("server.bad_size")
+	assert.True(t, errors.Is(err, ErrConvert))
+
+	_, err = config.GetIntE("server.missing")
+	assert.True(t, errors.Is(err, ErrKeyNotSet))
+	var notSetErr *KeyNotSetError
+	assert.True(t, stderrors.As(err, &notSetErr))
+	assert.Equal(t, "server.missing", notSetErr.Key)
+	assert.Equal(t, serverConfigFile, notSetErr.File)
+
+	_, err = config.GetStringE("log.level")
+	assert.Equal(t, "key [log.level] not set", err.Error())
+
+	_, err = Get[int](config, "server.port")
+	assert.True(t, errors.Is(err, ErrConvert))
+	_, err = Get[int](config, "server.missing")
+	assert.True(t, errors.Is(err, ErrKeyNotSet))
+}
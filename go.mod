@@ -1,12 +1,13 @@
 module github.com/ace-zhaoy/gviper
 
-go 1.18
+go 1.19
 
 require (
 	github.com/ace-zhaoy/errors v1.1.0
 	github.com/ace-zhaoy/go-utils v1.2.5
 	github.com/fsnotify/fsnotify v1.7.0
 	github.com/mitchellh/mapstructure v1.5.0
+	github.com/spf13/cast v1.6.0
 	github.com/spf13/viper v1.16.0
 	github.com/stretchr/testify v1.9.0
 )
@@ -18,7 +19,6 @@ require (
 	github.com/pelletier/go-toml/v2 v2.2.2 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/spf13/afero v1.11.0 // indirect
-	github.com/spf13/cast v1.6.0 // indirect
 	github.com/spf13/jwalterweatherman v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/subosito/gotenv v1.6.0 // indirect
@@ -0,0 +1,120 @@
+package gviper
+
+import (
+	"github.com/ace-zhaoy/errors"
+	"github.com/ace-zhaoy/go-utils/usafe"
+	"github.com/ace-zhaoy/go-utils/uslice"
+	"github.com/spf13/viper"
+	"strings"
+	"sync"
+)
+
+type Listener func(viper *viper.Viper) error
+
+type listener struct {
+	id       uint64
+	priority int
+	fn       EventListener
+}
+
+type Subscription struct {
+	once        sync.Once
+	unsubscribe func()
+}
+
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(s.unsubscribe)
+}
+
+func (c *Config) OnChange(name string, listener Listener) *Subscription {
+	return c.OnChangeWithPriority(name, 0, listener)
+}
+
+// OnChangeWithPriority registers a listener that runs before every listener
+// with a lower priority. Listeners with the same priority run in registration order.
+func (c *Config) OnChangeWithPriority(name string, priority int, listener Listener) *Subscription {
+	return c.OnChangeEventWithPriority(name, priority, func(event *ChangeEvent) error {
+		return listener(event.viper)
+	})
+}
+
+func (c *Config) OnChangeEvent(name string, listener EventListener) *Subscription {
+	return c.OnChangeEventWithPriority(name, 0, listener)
+}
+
+func (c *Config) OnChangeEventWithPriority(name string, priority int, listener EventListener) *Subscription {
+	cp := c.resolveConfigParam(name)
+	return c.addListener(cp, priority, listener)
+}
+
+// OnKeyChange registers a listener that only runs when the value at key differs
+// from the previous load. The first key segment selects the config, e.g.
+// "database.pool.size" or "database.replicas.*".
+func (c *Config) OnKeyChange(key string, listener EventListener) *Subscription {
+	cp, pattern := c.resolveKey(key)
+	return c.addListener(cp, 0, func(event *ChangeEvent) error {
+		if !event.Changed(pattern) {
+			return nil
+		}
+		return listener(event)
+	})
+}
+
+// resolveKey splits key into its config and the pattern below it. Config
+// names match case-insensitively, so the name is cut off by length.
+func (c *Config) resolveKey(key string) (*configParam, string) {
+	cp := c.findByKey(key)
+	if cp == nil {
+		cp = c.resolveConfigParam(strings.SplitN(key, ".", 2)[0])
+	}
+	return cp, strings.TrimPrefix(key[len(cp.configName):], ".")
+}
+
+func (c *Config) addListener(cp *configParam, priority int, fn EventListener) *Subscription {
+	c.listenerMu.Lock()
+	defer c.listenerMu.Unlock()
+	c.listenerSeq++
+	l := &listener{id: c.listenerSeq, priority: priority, fn: fn}
+	i := len(cp.listeners)
+	for j, v := range cp.listeners {
+		if v.priority < priority {
+			i = j
+			break
+		}
+	}
+	listeners := make([]*listener, 0, len(cp.listeners)+1)
+	listeners = append(listeners, cp.listeners[:i]...)
+	listeners = append(listeners, l)
+	cp.listeners = append(listeners, cp.listeners[i:]...)
+	return &Subscription{unsubscribe: func() { c.removeListener(cp, l.id) }}
+}
+
+func (c *Config) removeListener(cp *configParam, id uint64) {
+	c.listenerMu.Lock()
+	defer c.listenerMu.Unlock()
+	cp.listeners = uslice.Filter(cp.listeners, func(l *listener) bool { return l.id != id })
+}
+
+// getListeners returns the listeners of cp. The slice is replaced rather than
+// modified on (un)subscribe, so it can be used after the lock is released.
+func (c *Config) getListeners(cp *configParam) []*listener {
+	c.listenerMu.RLock()
+	defer c.listenerMu.RUnlock()
+	return cp.listeners
+}
+
+// fireListeners runs every listener even when some of them fail, and returns
+// the failures joined in listener order.
+func (c *Config) fireListeners(cp *configParam, event *ChangeEvent, listeners []*listener) error {
+	errs := make([]error, 0)
+	uslice.ForEach(listeners, func(l *listener) {
+		var err error
+		if e := usafe.Func(func() { err = l.fn(event) }); e != nil {
+			err = e
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	})
+	return errors.Wrap(errors.Join(errs...), "onchange config [%s] failed", cp.configName)
+}
@@ -0,0 +1,359 @@
+package gviper
+
+import (
+	"context"
+	"github.com/ace-zhaoy/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConfig_OnChange_multiple(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "server.yaml"), []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	var calls []string
+	record := func(name string) Listener {
+		return func(v *viper.Viper) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	config := NewConfig(d)
+	config.OnChange("server", record("first"))
+	second := config.OnChange("server", record("second"))
+	config.OnChangeWithPriority("server", 10, record("high"))
+	config.OnChangeWithPriority("server", -1, record("low"))
+	config.OnChange("server", record("third"))
+
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, []string{"high", "first", "second", "third", "low"}, calls)
+
+	calls = nil
+	second.Unsubscribe()
+	second.Unsubscribe()
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, []string{"high", "first", "third", "low"}, calls)
+}
+
+func TestConfig_OnChange_aggregateErrors(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "server.yaml"), []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	err1 := errors.New("listener 1 failed")
+	err2 := errors.New("listener 2 failed")
+	called := false
+
+	config := NewConfig(d)
+	config.OnChange("server", func(v *viper.Viper) error { return err1 })
+	config.OnChange("server", func(v *viper.Viper) error { panic(err2) })
+	config.OnChange("server", func(v *viper.Viper) error {
+		called = true
+		return nil
+	})
+
+	err = config.Load()
+	assert.True(t, called)
+	assert.True(t, errors.Is(err, errors.NewWithMessage("onchange config [%s] failed", "server")))
+	assert.True(t, errors.Is(err, err1))
+	assert.True(t, errors.Is(err, err2))
+}
+
+func TestConfig_OnKeyChange(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	databaseConfigFile := filepath.Join(d, "database.yaml")
+	err := os.WriteFile(databaseConfigFile, []byte("host: localhost\npool:\n  size: 10\nreplicas:\n  r1: a"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.yaml: %v", err)
+	}
+
+	var calls []string
+	config := NewConfig(d, "database", "log.toml")
+	config.OnKeyChange("database.pool.size", func(event *ChangeEvent) error {
+		calls = append(calls, "pool.size")
+		return nil
+	})
+	config.OnKeyChange("database.replicas.*", func(event *ChangeEvent) error {
+		calls = append(calls, "replicas")
+		return nil
+	})
+	config.OnKeyChange("database.host", func(event *ChangeEvent) error {
+		calls = append(calls, "host")
+		return nil
+	})
+	config.OnKeyChange("log.level", func(event *ChangeEvent) error {
+		calls = append(calls, "log")
+		return nil
+	})
+	config.OnKeyChange("server.name", func(event *ChangeEvent) error { return nil })
+	assert.Equal(t, 3, len(config.configs))
+	assert.Equal(t, "server", config.configs[2].configName)
+	config.configs = config.configs[:2]
+
+	err = os.WriteFile(filepath.Join(d, "log.toml"), []byte("level = \"info\""), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create log.toml: %v", err)
+	}
+
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, []string{"pool.size", "replicas", "host", "log"}, calls)
+
+	calls = nil
+	err = os.WriteFile(databaseConfigFile, []byte("host: localhost\npool:\n  size: 20\nreplicas:\n  r1: a"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write database.yaml: %v", err)
+	}
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, []string{"pool.size"}, calls)
+
+	calls = nil
+	err = os.WriteFile(databaseConfigFile, []byte("host: localhost\npool:\n  size: 20\nreplicas:\n  r1: a\n  r2: b"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write database.yaml: %v", err)
+	}
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, []string{"replicas"}, calls)
+}
+
+func TestConfig_OnKeyChange_mixedCase(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	databaseConfigFile := filepath.Join(d, "database.yaml")
+	err := os.WriteFile(databaseConfigFile, []byte("pool:\n  size: 10"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.yaml: %v", err)
+	}
+
+	calls := 0
+	config := NewConfig(d, "database")
+	config.OnKeyChange("Database.Pool.Size", func(event *ChangeEvent) error {
+		calls++
+		return nil
+	})
+	assert.Equal(t, 1, len(config.configs))
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	err = os.WriteFile(databaseConfigFile, []byte("pool:\n  size: 20"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write database.yaml: %v", err)
+	}
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestConfig_OnChange_reentrant(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "server.yaml"), []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(d, "log.yaml"), []byte("level: info"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create log.yaml: %v", err)
+	}
+
+	config := NewConfig(d, "server", "log")
+	logReloads := 0
+	config.OnChange("log", func(viper *viper.Viper) error {
+		logReloads++
+		return nil
+	})
+	config.OnChange("server", func(viper *viper.Viper) error {
+		assert.Equal(t, "gviper", config.GetString("server.name"))
+		return config.Reload("log")
+	})
+
+	done := make(chan error)
+	go func() { done <- config.Load() }()
+	select {
+	case err = <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Expected Load not to deadlock when a listener reloads another config")
+	}
+	assert.Equal(t, 2, logReloads)
+}
+
+func TestConfig_OnChange_stopFromListener(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	config := NewConfig(d, "server")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	stopped := make(chan struct{})
+	config.OnChange("server", func(viper *viper.Viper) error {
+		config.Stop()
+		close(stopped)
+		return nil
+	})
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	w := config.watcher
+
+	err = replaceFile(serverConfigFile, []byte("name: gviper2"))
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Stop to return when called from a listener")
+	}
+	select {
+	case <-w.done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected watch goroutine to exit")
+	}
+	assert.NoError(t, config.Close())
+}
+
+func TestConfig_Close_waitsForListener(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	config := NewConfig(d, "server")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	config.OnChange("server", func(viper *viper.Viper) error {
+		close(entered)
+		<-release
+		return nil
+	})
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	w := config.watcher
+
+	err = replaceFile(serverConfigFile, []byte("name: gviper2"))
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	<-entered
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		_ = config.Close()
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Expected Close to wait for the listener")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-closed
+	select {
+	case <-w.done:
+	default:
+		t.Fatal("Expected watch goroutine to have exited")
+	}
+}
+
+func TestConfig_Reload_ordered(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("a: 0"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	config := NewConfig(d, "server")
+	var mu sync.Mutex
+	var seen []int
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	config.OnChange("server", func(viper *viper.Viper) error {
+		if viper.GetInt("a") == 1 {
+			close(entered)
+			<-release
+		}
+		return nil
+	})
+	config.OnChange("server", func(viper *viper.Viper) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, viper.GetInt("a"))
+		return nil
+	})
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	err = os.WriteFile(serverConfigFile, []byte("a: 1"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	first := make(chan error)
+	go func() { first <- config.Reload("server") }()
+	<-entered
+
+	err = os.WriteFile(serverConfigFile, []byte("a: 2"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	second := make(chan error)
+	go func() { second <- config.Reload("server") }()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	assert.NoError(t, <-first)
+	assert.NoError(t, <-second)
+	assert.Equal(t, []int{0, 1, 2}, seen)
+	assert.Equal(t, 2, config.GetInt("server.a"))
+}
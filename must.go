@@ -0,0 +1,105 @@
+package gviper
+
+import (
+	"github.com/ace-zhaoy/errors"
+	"github.com/ace-zhaoy/go-utils/uslice"
+	"time"
+)
+
+// Require declares keys that must be set once every registered file is read.
+// Load reports all of them that are missing in a single error.
+func (c *Config) Require(keys ...string) {
+	c.required = append(c.required, keys...)
+}
+
+func (c *Config) checkRequired() error {
+	v := c.viper.Load()
+	errs := make([]error, 0)
+	uslice.ForEach(c.required, func(key string) {
+		if !v.IsSet(key) {
+			errs = append(errs, &KeyNotSetError{Key: key, File: c.fileOf(key)})
+		}
+	})
+	return errors.Wrap(errors.Join(errs...), "required keys missing")
+}
+
+func must[T any](value T, err error) T {
+	errors.Check(err)
+	return value
+}
+
+func MustGet[T any](c *Config, key string) T {
+	return must(Get[T](c, key))
+}
+
+func (c *Config) MustGet(key string) any {
+	return must(c.GetE(key))
+}
+
+func (c *Config) MustGetString(key string) string {
+	return must(c.GetStringE(key))
+}
+
+func (c *Config) MustGetBool(key string) bool {
+	return must(c.GetBoolE(key))
+}
+
+func (c *Config) MustGetInt(key string) int {
+	return must(c.GetIntE(key))
+}
+
+func (c *Config) MustGetInt32(key string) int32 {
+	return must(c.GetInt32E(key))
+}
+
+func (c *Config) MustGetInt64(key string) int64 {
+	return must(c.GetInt64E(key))
+}
+
+func (c *Config) MustGetUint(key string) uint {
+	return must(c.GetUintE(key))
+}
+
+func (c *Config) MustGetUint32(key string) uint32 {
+	return must(c.GetUint32E(key))
+}
+
+func (c *Config) MustGetUint64(key string) uint64 {
+	return must(c.GetUint64E(key))
+}
+
+func (c *Config) MustGetFloat64(key string) float64 {
+	return must(c.GetFloat64E(key))
+}
+
+func (c *Config) MustGetTime(key string) time.Time {
+	return must(c.GetTimeE(key))
+}
+
+func (c *Config) MustGetDuration(key string) time.Duration {
+	return must(c.GetDurationE(key))
+}
+
+func (c *Config) MustGetIntSlice(key string) []int {
+	return must(c.GetIntSliceE(key))
+}
+
+func (c *Config) MustGetStringSlice(key string) []string {
+	return must(c.GetStringSliceE(key))
+}
+
+func (c *Config) MustGetStringMap(key string) map[string]any {
+	return must(c.GetStringMapE(key))
+}
+
+func (c *Config) MustGetStringMapString(key string) map[string]string {
+	return must(c.GetStringMapStringE(key))
+}
+
+func (c *Config) MustGetStringMapStringSlice(key string) map[string][]string {
+	return must(c.GetStringMapStringSliceE(key))
+}
+
+func (c *Config) MustGetSizeInBytes(key string) uint {
+	return must(c.GetSizeInBytesE(key))
+}
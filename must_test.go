@@ -0,0 +1,68 @@
+package gviper
+
+import (
+	"github.com/ace-zhaoy/errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_Require(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	databaseConfigFile := filepath.Join(d, "database.yaml")
+	err := os.WriteFile(databaseConfigFile, []byte("host: localhost"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.yaml: %v", err)
+	}
+	logConfigFile := filepath.Join(d, "log.toml")
+	err = os.WriteFile(logConfigFile, []byte("type = \"console\""), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create log.toml: %v", err)
+	}
+
+	config := NewConfig(d, "database", "log.toml")
+	config.Require("database.host", "database.port", "database.user")
+	config.Require("log.level", "log.type")
+	err = config.Load()
+	assert.True(t, errors.Is(err, ErrKeyNotSet))
+	assert.True(t, errors.Is(err, errors.NewWithMessage("required keys missing")))
+	assert.Contains(t, err.Error(), "key [database.port] not set in config file ["+databaseConfigFile+"]")
+	assert.Contains(t, err.Error(), "key [database.user] not set in config file ["+databaseConfigFile+"]")
+	assert.Contains(t, err.Error(), "key [log.level] not set in config file ["+logConfigFile+"]")
+	assert.NotContains(t, err.Error(), "database.host")
+	assert.NotContains(t, err.Error(), "log.type")
+
+	err = os.WriteFile(databaseConfigFile, []byte("host: localhost\nport: 5432\nuser: root"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write database.yaml: %v", err)
+	}
+	err = os.WriteFile(logConfigFile, []byte("type = \"console\"\nlevel = \"info\""), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write log.toml: %v", err)
+	}
+	assert.NoError(t, config.Load())
+}
+
+func TestConfig_MustGet(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "server.yaml"), []byte("name: gviper\nport: 8080\nbad_port: abc"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	config := NewConfig(d, "server")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	assert.Equal(t, "gviper", config.MustGetString("server.name"))
+	assert.Equal(t, 8080, config.MustGetInt("server.port"))
+	assert.Equal(t, uint16(8080), MustGet[uint16](config, "server.port"))
+	assert.Panics(t, func() { config.MustGetInt("server.bad_port") })
+	assert.Panics(t, func() { config.MustGetString("server.missing") })
+	assert.Panics(t, func() { MustGet[int](config, "server.missing") })
+}
@@ -1,5 +1,163 @@
 package gviper
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/ace-zhaoy/go-utils/uslice"
+	"os"
+	"time"
+)
+
 type Notification interface {
 	Notify(configName string, err error)
 }
+
+type NotifyKind int
+
+const (
+	// NotifyLoadFailed is sent when a config fails to load before it was ever
+	// loaded successfully.
+	NotifyLoadFailed NotifyKind = iota + 1
+	// NotifyReloadFailed is sent when reloading a loaded config fails. The
+	// last good values stay live.
+	NotifyReloadFailed
+	NotifyReloadSucceeded
+	// NotifyRecovered is sent instead of NotifyReloadSucceeded for the first
+	// successful load after a failure.
+	NotifyRecovered
+	NotifyWatchError
+	NotifyUnusedKeys
+	// NotifyRemoved is sent when the file of a loaded config is deleted and
+	// the remove policy was applied.
+	NotifyRemoved
+)
+
+func (k NotifyKind) String() string {
+	switch k {
+	case NotifyLoadFailed:
+		return "load failed"
+	case NotifyReloadFailed:
+		return "reload failed"
+	case NotifyReloadSucceeded:
+		return "reload succeeded"
+	case NotifyRecovered:
+		return "recovered"
+	case NotifyWatchError:
+		return "watch error"
+	case NotifyUnusedKeys:
+		return "unused keys"
+	case NotifyRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+var hostname, _ = os.Hostname()
+
+// NotifyEvent carries what on-call needs to act on a notification. File and
+// Version are empty for events not tied to a config, such as watch errors.
+type NotifyEvent struct {
+	Kind       NotifyKind
+	ConfigName string
+	File       string
+	Err        error
+	// Version is the sha256 of the file content that was loaded, in hex. A
+	// failure reports the version still live.
+	Version string
+	// ChangedKeys lists the keys, relative to the config, that changed.
+	ChangedKeys []string
+	Duration    time.Duration
+	// Attempt counts the consecutive load attempts up to this event: 1 for a
+	// first try, n for the nth failure in a row or a recovery after n-1.
+	Attempt int
+	Time    time.Time
+	Host    string
+	PID     int
+}
+
+// EventNotifier receives every NotifyEvent. A Notification that also
+// implements EventNotifier gets NotifyEvent instead of Notify.
+type EventNotifier interface {
+	NotifyEvent(event *NotifyEvent)
+}
+
+func newNotifyEvent(kind NotifyKind, cp *configParam, err error) *NotifyEvent {
+	event := &NotifyEvent{
+		Kind:        kind,
+		Err:         err,
+		ChangedKeys: []string{},
+		Time:        time.Now(),
+		Host:        hostname,
+		PID:         os.Getpid(),
+	}
+	if cp != nil {
+		event.ConfigName = cp.configName
+		event.File = cp.configFile
+		if cp.version != [sha256.Size]byte{} {
+			event.Version = hex.EncodeToString(cp.version[:])
+		}
+	}
+	return event
+}
+
+// NotificationAdapter turns a Notification into an EventNotifier. Only events
+// carrying an error are forwarded to Notify.
+type NotificationAdapter struct {
+	Notification Notification
+}
+
+func (a NotificationAdapter) NotifyEvent(event *NotifyEvent) {
+	if event.Err != nil {
+		a.Notification.Notify(event.ConfigName, event.Err)
+	}
+}
+
+func (c *Config) RegisterEventNotifier(notifiers ...EventNotifier) {
+	c.eventNotifiers = append(c.eventNotifiers, notifiers...)
+}
+
+func (c *Config) deliver(event *NotifyEvent) {
+	uslice.ForEach(c.eventNotifiers, func(n EventNotifier) { n.NotifyEvent(event) })
+	uslice.ForEach(c.notifications, func(n Notification) {
+		if en, ok := n.(EventNotifier); ok {
+			en.NotifyEvent(event)
+			return
+		}
+		NotificationAdapter{Notification: n}.NotifyEvent(event)
+	})
+}
+
+// report sends the outcome of loading cp to the notifiers and tracks whether
+// cp is failing, so the next success is reported as a recovery.
+func (c *Config) report(cp *configParam, start time.Time, change *ChangeEvent, err error) {
+	c.mu.Lock()
+	event := newNotifyEvent(0, cp, err)
+	event.Duration = time.Since(start)
+	event.Attempt = cp.failures + 1
+	if change != nil {
+		event.ChangedKeys = change.ChangedKeys()
+	}
+	switch {
+	case err != nil && !cp.loaded:
+		event.Kind = NotifyLoadFailed
+	case err != nil:
+		event.Kind = NotifyReloadFailed
+	case change != nil && change.Kind == ChangeRemoved:
+		event.Kind = NotifyRemoved
+	case cp.failures > 0:
+		event.Kind = NotifyRecovered
+	case cp.loaded:
+		event.Kind = NotifyReloadSucceeded
+	}
+	if err != nil {
+		cp.failures++
+	} else {
+		cp.failures = 0
+		cp.loaded = true
+	}
+	c.mu.Unlock()
+	if event.Kind != 0 {
+		c.dispatch(event)
+	}
+}
@@ -1,7 +1,12 @@
 package gviper
 
 import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 type MockNotification struct {
@@ -20,3 +25,195 @@ func TestNotification(t *testing.T) {
 		t.Error("Expected notification to be sent, but it was not")
 	}
 }
+
+type notificationFunc func(configName string, err error)
+
+func (f notificationFunc) Notify(configName string, err error) {
+	f(configName, err)
+}
+
+type eventNotifierFunc func(event *NotifyEvent)
+
+func (f eventNotifierFunc) NotifyEvent(event *NotifyEvent) {
+	f(event)
+}
+
+func TestConfig_RegisterEventNotifier(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+
+	var events []NotifyKind
+	var notified []string
+	config := NewConfigWithOptions(
+		WithConfigPath(d),
+		WithNotification(notificationFunc(func(configName string, err error) {
+			assert.Error(t, err)
+			notified = append(notified, configName)
+		})),
+	)
+	config.RegisterEventNotifier(eventNotifierFunc(func(event *NotifyEvent) {
+		assert.Equal(t, "server", event.ConfigName)
+		events = append(events, event.Kind)
+	}))
+	config.Register("server")
+
+	err := config.Load()
+	assert.Error(t, err)
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, []NotifyKind{NotifyLoadFailed}, events)
+	assert.Equal(t, []string{"server"}, notified)
+
+	err = os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	err = config.Reload("server")
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, []NotifyKind{NotifyLoadFailed, NotifyRecovered, NotifyReloadSucceeded}, events)
+
+	err = os.WriteFile(serverConfigFile, []byte("name: ["), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	assert.Error(t, config.Reload("server"))
+	assert.Error(t, config.Reload("server"))
+	err = os.WriteFile(serverConfigFile, []byte("name: gviper2"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	assert.NoError(t, config.Reload("server"))
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, []NotifyKind{NotifyLoadFailed, NotifyRecovered, NotifyReloadSucceeded, NotifyReloadFailed, NotifyReloadFailed, NotifyRecovered}, events)
+	assert.Equal(t, []string{"server", "server", "server"}, notified)
+}
+
+func TestConfig_Watch_notifyEvents(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	ch := make(chan *NotifyEvent, 10)
+	config := NewConfigWithOptions(
+		WithConfigPath(d),
+		WithEventNotifier(eventNotifierFunc(func(event *NotifyEvent) { ch <- event })),
+	)
+	config.Register("server")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+
+	err = replaceFile(serverConfigFile, []byte("name: gviper2"))
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	select {
+	case event := <-ch:
+		assert.Equal(t, NotifyReloadSucceeded, event.Kind)
+		assert.NoError(t, event.Err)
+	case <-time.After(time.Second):
+		t.Fatal("Expected notify event")
+	}
+}
+
+func TestNotifyKind_String(t *testing.T) {
+	assert.Equal(t, "load failed", NotifyLoadFailed.String())
+	assert.Equal(t, "reload failed", NotifyReloadFailed.String())
+	assert.Equal(t, "reload succeeded", NotifyReloadSucceeded.String())
+	assert.Equal(t, "recovered", NotifyRecovered.String())
+	assert.Equal(t, "watch error", NotifyWatchError.String())
+	assert.Equal(t, "unused keys", NotifyUnusedKeys.String())
+	assert.Equal(t, "removed", NotifyRemoved.String())
+	assert.Equal(t, "unknown", NotifyKind(0).String())
+}
+
+type mockEventNotification struct {
+	MockNotification
+	events []*NotifyEvent
+}
+
+func (m *mockEventNotification) NotifyEvent(event *NotifyEvent) {
+	m.events = append(m.events, event)
+}
+
+func TestConfig_notifyEventFields(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper\nport: 8080"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	notification := &mockEventNotification{}
+	config := NewConfigWithOptions(WithConfigPath(d), WithNotification(notification))
+	config.Register("server")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, 0, len(notification.events))
+
+	err = os.WriteFile(serverConfigFile, []byte("name: gviper2\nport: 8080"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	assert.NoError(t, config.Reload("server"))
+	err = os.WriteFile(serverConfigFile, []byte("name: ["), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	assert.Error(t, config.Reload("server"))
+	assert.Error(t, config.Reload("server"))
+	err = os.WriteFile(serverConfigFile, []byte("name: gviper3"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	assert.NoError(t, config.Reload("server"))
+
+	hostname, _ := os.Hostname()
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.False(t, notification.notified)
+	assert.Equal(t, 4, len(notification.events))
+	event := notification.events[0]
+	assert.Equal(t, NotifyReloadSucceeded, event.Kind)
+	assert.Equal(t, "server", event.ConfigName)
+	assert.Equal(t, serverConfigFile, event.File)
+	assert.Equal(t, []string{"name"}, event.ChangedKeys)
+	assert.Equal(t, 1, event.Attempt)
+	assert.Equal(t, 64, len(event.Version))
+	assert.Equal(t, hostname, event.Host)
+	assert.Equal(t, os.Getpid(), event.PID)
+	assert.False(t, event.Time.IsZero())
+
+	event = notification.events[2]
+	assert.Equal(t, NotifyReloadFailed, event.Kind)
+	assert.Error(t, event.Err)
+	assert.Equal(t, []string{}, event.ChangedKeys)
+	assert.Equal(t, 2, event.Attempt)
+	assert.Equal(t, notification.events[0].Version, event.Version)
+
+	event = notification.events[3]
+	assert.Equal(t, NotifyRecovered, event.Kind)
+	assert.Equal(t, []string{"name", "port"}, event.ChangedKeys)
+	assert.Equal(t, 3, event.Attempt)
+	assert.NotEqual(t, notification.events[0].Version, event.Version)
+}
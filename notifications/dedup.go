@@ -0,0 +1,123 @@
+package notifications
+
+import (
+	"fmt"
+	"github.com/ace-zhaoy/gviper"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DedupNotifier is a gviper.EventNotifier that keeps a flapping config from
+// flooding next. Only the first of identical load or reload failures of a
+// config within the window is forwarded; the rest are summarized in a single "N more failures"
+// digest when the window ends. Once a failing config loads again, one
+// recovered event is forwarded. Other events pass through unchanged.
+type DedupNotifier struct {
+	next    gviper.EventNotifier
+	window  time.Duration
+	mu      sync.Mutex
+	pending map[dedupKey]*dedupState
+	failing map[string]bool
+}
+
+type dedupKey struct {
+	configName string
+	err        string
+}
+
+type dedupState struct {
+	last       *gviper.NotifyEvent
+	suppressed int
+	timer      *time.Timer
+}
+
+func NewDedupNotifier(next gviper.EventNotifier, window time.Duration) *DedupNotifier {
+	return &DedupNotifier{
+		next:    next,
+		window:  window,
+		pending: make(map[dedupKey]*dedupState),
+		failing: make(map[string]bool),
+	}
+}
+
+func (d *DedupNotifier) NotifyEvent(event *gviper.NotifyEvent) {
+	d.forward(d.filter(event))
+}
+
+// forward hands events to next. It is called without d.mu, so a slow next
+// never blocks other callers and may call back into d.
+func (d *DedupNotifier) forward(events []*gviper.NotifyEvent) {
+	for _, event := range events {
+		d.next.NotifyEvent(event)
+	}
+}
+
+// filter records event and returns what should be forwarded for it.
+func (d *DedupNotifier) filter(event *gviper.NotifyEvent) []*gviper.NotifyEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case event.Kind == gviper.NotifyLoadFailed || event.Kind == gviper.NotifyReloadFailed:
+		d.failing[event.ConfigName] = true
+		key := dedupKey{configName: event.ConfigName, err: event.Err.Error()}
+		if state, ok := d.pending[key]; ok {
+			state.last = event
+			state.suppressed++
+			return nil
+		}
+		state := &dedupState{last: event}
+		state.timer = time.AfterFunc(d.window, func() { d.expire(key, state) })
+		d.pending[key] = state
+		return []*gviper.NotifyEvent{event}
+	case event.Kind == gviper.NotifyRecovered:
+		if !d.failing[event.ConfigName] {
+			return nil
+		}
+		delete(d.failing, event.ConfigName)
+		digests := d.flush(func(key dedupKey) bool { return key.configName == event.ConfigName })
+		return append(digests, event)
+	default:
+		return []*gviper.NotifyEvent{event}
+	}
+}
+
+// expire closes the window of state, unless it was already closed by a
+// recovery or Flush while the timer was firing.
+func (d *DedupNotifier) expire(key dedupKey, state *dedupState) {
+	d.mu.Lock()
+	var digests []*gviper.NotifyEvent
+	if d.pending[key] == state {
+		digests = d.flush(func(k dedupKey) bool { return k == key })
+	}
+	d.mu.Unlock()
+	d.forward(digests)
+}
+
+// Flush sends the digests of every open window right away, e.g. on shutdown.
+func (d *DedupNotifier) Flush() {
+	d.mu.Lock()
+	digests := d.flush(func(dedupKey) bool { return true })
+	d.mu.Unlock()
+	d.forward(digests)
+}
+
+// flush closes the windows matching match and returns their digests in order
+// of the failures they summarize. Callers hold d.mu.
+func (d *DedupNotifier) flush(match func(key dedupKey) bool) []*gviper.NotifyEvent {
+	digests := make([]*gviper.NotifyEvent, 0)
+	for key, state := range d.pending {
+		if !match(key) {
+			continue
+		}
+		state.timer.Stop()
+		delete(d.pending, key)
+		if state.suppressed > 0 {
+			digest := *state.last
+			digest.Err = fmt.Errorf("%d more failures: %w", state.suppressed, state.last.Err)
+			digests = append(digests, &digest)
+		}
+	}
+	sort.Slice(digests, func(i, j int) bool { return digests[i].Time.Before(digests[j].Time) })
+	return digests
+}
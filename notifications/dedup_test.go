@@ -0,0 +1,173 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ace-zhaoy/gviper"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []*gviper.NotifyEvent
+}
+
+func (r *recordingNotifier) NotifyEvent(event *gviper.NotifyEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recordingNotifier) messages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	messages := make([]string, 0, len(r.events))
+	for _, event := range r.events {
+		if event.Err == nil {
+			messages = append(messages, fmt.Sprintf("%s %s", event.ConfigName, event.Kind))
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("%s %s: %v", event.ConfigName, event.Kind, event.Err))
+	}
+	return messages
+}
+
+type notifierFunc func(event *gviper.NotifyEvent)
+
+func (f notifierFunc) NotifyEvent(event *gviper.NotifyEvent) {
+	f(event)
+}
+
+func failed(configName string, err string) *gviper.NotifyEvent {
+	return &gviper.NotifyEvent{Kind: gviper.NotifyReloadFailed, ConfigName: configName, Err: errors.New(err), Time: time.Now()}
+}
+
+func TestDedupNotifier(t *testing.T) {
+	next := &recordingNotifier{}
+	d := NewDedupNotifier(next, time.Hour)
+
+	d.NotifyEvent(failed("server", "bad port"))
+	d.NotifyEvent(failed("server", "bad port"))
+	d.NotifyEvent(failed("server", "bad port"))
+	d.NotifyEvent(failed("log", "bad port"))
+	d.NotifyEvent(failed("server", "bad host"))
+	assert.Equal(t, []string{
+		"server reload failed: bad port",
+		"log reload failed: bad port",
+		"server reload failed: bad host",
+	}, next.messages())
+
+	d.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyReloadSucceeded, ConfigName: "app"})
+	d.Flush()
+	assert.Equal(t, []string{
+		"server reload failed: bad port",
+		"log reload failed: bad port",
+		"server reload failed: bad host",
+		"app reload succeeded",
+		"server reload failed: 2 more failures: bad port",
+	}, next.messages())
+
+	d.NotifyEvent(failed("server", "bad port"))
+	assert.Equal(t, "server reload failed: bad port", next.messages()[5])
+}
+
+func TestDedupNotifier_window(t *testing.T) {
+	next := &recordingNotifier{}
+	d := NewDedupNotifier(next, 20*time.Millisecond)
+
+	d.NotifyEvent(failed("server", "bad port"))
+	d.NotifyEvent(failed("server", "bad port"))
+	assert.Equal(t, 1, len(next.messages()))
+	assert.Eventually(t, func() bool { return len(next.messages()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "server reload failed: 1 more failures: bad port", next.messages()[1])
+
+	d.NotifyEvent(failed("server", "bad port"))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []string{
+		"server reload failed: bad port",
+		"server reload failed: 1 more failures: bad port",
+		"server reload failed: bad port",
+	}, next.messages())
+}
+
+func TestDedupNotifier_recovered(t *testing.T) {
+	next := &recordingNotifier{}
+	d := NewDedupNotifier(next, time.Hour)
+
+	d.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyRecovered, ConfigName: "server"})
+	assert.Equal(t, 0, len(next.messages()))
+
+	d.NotifyEvent(failed("server", "bad port"))
+	d.NotifyEvent(failed("server", "bad port"))
+	d.NotifyEvent(failed("log", "bad level"))
+	d.NotifyEvent(failed("log", "bad level"))
+	d.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyRecovered, ConfigName: "server"})
+	d.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyRecovered, ConfigName: "server"})
+	assert.Equal(t, []string{
+		"server reload failed: bad port",
+		"log reload failed: bad level",
+		"server reload failed: 1 more failures: bad port",
+		"server recovered",
+	}, next.messages())
+
+	d.NotifyEvent(failed("server", "bad port"))
+	assert.Equal(t, "server reload failed: bad port", next.messages()[4])
+}
+
+func TestDedupNotifier_slowNext(t *testing.T) {
+	release := make(chan struct{})
+	next := &recordingNotifier{}
+	var d *DedupNotifier
+	d = NewDedupNotifier(notifierFunc(func(event *gviper.NotifyEvent) {
+		if event.ConfigName == "server" {
+			<-release
+			d.Flush()
+		}
+		next.NotifyEvent(event)
+	}), time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.NotifyEvent(failed("server", "boom"))
+	}()
+	notified := make(chan struct{})
+	go func() {
+		defer close(notified)
+		d.NotifyEvent(failed("log", "boom"))
+	}()
+	select {
+	case <-notified:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a slow next not to block other events")
+	}
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected next to be able to call back into the notifier")
+	}
+	assert.Equal(t, []string{"log reload failed: boom", "server reload failed: boom"}, next.messages())
+}
+
+func TestDedupNotifier_otherKinds(t *testing.T) {
+	next := &recordingNotifier{}
+	d := NewDedupNotifier(next, time.Minute)
+	unused := &gviper.NotifyEvent{Kind: gviper.NotifyUnusedKeys, ConfigName: "server", Err: errors.New("unused keys [port]")}
+	watchErr := &gviper.NotifyEvent{Kind: gviper.NotifyWatchError, Err: errors.New("overflow")}
+	d.NotifyEvent(unused)
+	d.NotifyEvent(unused)
+	d.NotifyEvent(watchErr)
+	d.NotifyEvent(watchErr)
+	d.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyRecovered, ConfigName: "server"})
+	d.Flush()
+	assert.Equal(t, []string{
+		"server unused keys: unused keys [port]",
+		"server unused keys: unused keys [port]",
+		" watch error: overflow",
+		" watch error: overflow",
+	}, next.messages())
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"github.com/ace-zhaoy/gviper"
 	"io"
 	"log"
 	"net/http"
@@ -29,34 +30,63 @@ func (f *FeishuBotHook) buildPayload(configName string, err error) io.Reader {
 		return f.payloadBuilder(configName, err)
 	}
 
-	return bytes.NewBuffer([]byte(fmt.Sprintf(
-		`{"msg_type":"text","content":{"text":"%s"}}`,
-		fmt.Sprintf("Config %s reload failed: %v", configName, err),
-	)))
+	return f.textPayload(fmt.Sprintf("Config %s reload failed: %v", configName, err))
+}
+
+func (f *FeishuBotHook) buildEventPayload(event *gviper.NotifyEvent) io.Reader {
+	if f.payloadBuilder != nil {
+		return f.payloadBuilder(event.ConfigName, event.Err)
+	}
+	if event.Err == nil {
+		return f.textPayload(fmt.Sprintf("Config %s %s", event.ConfigName, event.Kind))
+	}
+	return f.textPayload(fmt.Sprintf("Config %s %s: %v", event.ConfigName, event.Kind, event.Err))
+}
+
+func (f *FeishuBotHook) textPayload(text string) io.Reader {
+	type Content struct {
+		Text string `json:"text"`
+	}
+	type Payload struct {
+		MsgType string  `json:"msg_type"`
+		Content Content `json:"content"`
+	}
+	payload, _ := json.Marshal(Payload{MsgType: "text", Content: Content{Text: text}})
+	return bytes.NewBuffer(payload)
 }
 
 func (f *FeishuBotHook) Notify(configName string, err error) {
-	resp, err1 := http.Post(f.webhookAddr, "application/json", f.buildPayload(configName, err))
-	if err1 != nil {
+	if err1 := f.post(f.buildPayload(configName, err)); err1 != nil {
 		log.Printf("feishu bot notify failed: %v", err1)
-		return
+	}
+}
+
+// Deliver sends event and returns why it was not delivered, as a *StatusError
+// when the webhook answered with a non-200 status or a non-zero code.
+func (f *FeishuBotHook) Deliver(event *gviper.NotifyEvent) error {
+	return f.post(f.buildEventPayload(event))
+}
+
+func (f *FeishuBotHook) post(payload io.Reader) error {
+	resp, err := http.Post(f.webhookAddr, "application/json", payload)
+	if err != nil {
+		return err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		log.Printf("feishu bot notify failed: %v", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
 	}
 	type Result struct {
 		Code int    `json:"code"`
 		Msg  string `json:"msg"`
 	}
 	var result Result
-	err1 = json.NewDecoder(resp.Body).Decode(&result)
-	if err1 != nil {
-		log.Printf("feishu bot notify failed: %v", err1)
-		return
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return err
 	}
 	if result.Code != 0 {
-		log.Printf("feishu bot notify failed: %v %v", result.Code, result.Msg)
-		return
+		return &StatusError{StatusCode: resp.StatusCode, Code: result.Code, Msg: result.Msg}
 	}
+	return nil
 }
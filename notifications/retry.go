@@ -0,0 +1,300 @@
+package notifications
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ace-zhaoy/gviper"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Deliverer sends a single event and returns why it was not delivered.
+type Deliverer interface {
+	Deliver(event *gviper.NotifyEvent) error
+}
+
+type StatusError struct {
+	StatusCode int
+	Code       int
+	Msg        string
+	// RetryAfter is the delay asked for by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("webhook responded with code %d: %s", e.Code, e.Msg)
+	}
+	return fmt.Sprintf("webhook responded with status %d", e.StatusCode)
+}
+
+// IsRetryable treats network errors, 429 and 5xx responses as temporary.
+func IsRetryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && time.Until(t) > 0 {
+		return time.Until(t)
+	}
+	return 0
+}
+
+type RetryOption func(*RetryNotifier)
+
+func WithMaxAttempts(maxAttempts int) RetryOption {
+	return func(r *RetryNotifier) {
+		r.maxAttempts = maxAttempts
+	}
+}
+
+// WithBackoff sets the delay before the first retry, doubled on every
+// further retry up to maxDelay. maxDelay also caps Retry-After.
+func WithBackoff(initialDelay, maxDelay time.Duration) RetryOption {
+	return func(r *RetryNotifier) {
+		r.initialDelay = initialDelay
+		r.maxDelay = maxDelay
+	}
+}
+
+func WithRetryable(retryable func(err error) bool) RetryOption {
+	return func(r *RetryNotifier) {
+		r.retryable = retryable
+	}
+}
+
+// WithDeadLetterFile appends events that could not be delivered to path, one
+// JSON object per line, so Replay can send them later.
+func WithDeadLetterFile(path string) RetryOption {
+	return func(r *RetryNotifier) {
+		r.deadLetterFile = path
+	}
+}
+
+// RetryNotifier is a gviper.EventNotifier that delivers failures and
+// recoveries through a Deliverer, retrying temporary errors with exponential
+// backoff and honoring Retry-After up to the maximum delay. NotifyEvent blocks while it waits between
+// attempts, which holds up the dispatcher worker and every notification queued
+// behind it; Close interrupts the wait.
+type RetryNotifier struct {
+	deliverer      Deliverer
+	maxAttempts    int
+	initialDelay   time.Duration
+	maxDelay       time.Duration
+	retryable      func(err error) bool
+	deadLetterFile string
+	mu             sync.Mutex
+	sleep          func(d time.Duration) bool
+	stop           chan struct{}
+	stopOnce       sync.Once
+}
+
+func NewRetryNotifier(deliverer Deliverer, options ...RetryOption) *RetryNotifier {
+	r := &RetryNotifier{
+		deliverer:    deliverer,
+		maxAttempts:  5,
+		initialDelay: time.Second,
+		maxDelay:     time.Minute,
+		retryable:    IsRetryable,
+		stop:         make(chan struct{}),
+	}
+	r.sleep = r.wait
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+func (r *RetryNotifier) NotifyEvent(event *gviper.NotifyEvent) {
+	if event.Err == nil && event.Kind != gviper.NotifyRecovered {
+		return
+	}
+	err := r.deliver(event)
+	if err == nil {
+		return
+	}
+	log.Printf("notify config %s failed: %v", event.ConfigName, err)
+	if r.deadLetterFile == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err = r.appendDeadLetters([]*deadLetter{newDeadLetter(event)}); err != nil {
+		log.Printf("write dead letter file [%s] failed: %v", r.deadLetterFile, err)
+	}
+}
+
+func (r *RetryNotifier) deliver(event *gviper.NotifyEvent) error {
+	delay := r.initialDelay
+	for attempt := 1; ; attempt++ {
+		err := r.deliverer.Deliver(event)
+		if err == nil || attempt >= r.maxAttempts || !r.retryable(err) {
+			return err
+		}
+		wait := delay
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > wait {
+			wait = statusErr.RetryAfter
+		}
+		if wait > r.maxDelay {
+			wait = r.maxDelay
+		}
+		if !r.sleep(wait) {
+			return err
+		}
+		delay *= 2
+		if delay > r.maxDelay {
+			delay = r.maxDelay
+		}
+	}
+}
+
+// wait sleeps for d and reports false if Close was called meanwhile.
+func (r *RetryNotifier) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.stop:
+		return false
+	}
+}
+
+// Close stops retrying: a delivery waiting for its next attempt gives up and
+// goes to the dead letter file, and later deliveries are attempted once.
+func (r *RetryNotifier) Close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+// Replay delivers the events left in the dead letter file, e.g. on start.
+// Events that still fail stay in the file.
+func (r *RetryNotifier) Replay() error {
+	if r.deadLetterFile == "" {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := os.ReadFile(r.deadLetterFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := make([]*deadLetter, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var letter deadLetter
+		if err = json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			log.Printf("skip malformed dead letter: %v", err)
+			continue
+		}
+		if err = r.deliver(letter.event()); err != nil {
+			failed = append(failed, &letter)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	if err = os.Remove(r.deadLetterFile); err != nil {
+		return err
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	if err = r.appendDeadLetters(failed); err != nil {
+		return err
+	}
+	return fmt.Errorf("%d dead letters still undelivered", len(failed))
+}
+
+func (r *RetryNotifier) appendDeadLetters(letters []*deadLetter) error {
+	f, err := os.OpenFile(r.deadLetterFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	for _, letter := range letters {
+		if err = encoder.Encode(letter); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+type deadLetter struct {
+	Kind        gviper.NotifyKind `json:"kind"`
+	ConfigName  string            `json:"config_name"`
+	File        string            `json:"file"`
+	Error       string            `json:"error,omitempty"`
+	Version     string            `json:"version"`
+	ChangedKeys []string          `json:"changed_keys"`
+	Duration    time.Duration     `json:"duration"`
+	Attempt     int               `json:"attempt"`
+	Time        time.Time         `json:"time"`
+	Host        string            `json:"host"`
+	PID         int               `json:"pid"`
+}
+
+func newDeadLetter(event *gviper.NotifyEvent) *deadLetter {
+	letter := &deadLetter{
+		Kind:        event.Kind,
+		ConfigName:  event.ConfigName,
+		File:        event.File,
+		Version:     event.Version,
+		ChangedKeys: event.ChangedKeys,
+		Duration:    event.Duration,
+		Attempt:     event.Attempt,
+		Time:        event.Time,
+		Host:        event.Host,
+		PID:         event.PID,
+	}
+	if event.Err != nil {
+		letter.Error = event.Err.Error()
+	}
+	return letter
+}
+
+func (l *deadLetter) event() *gviper.NotifyEvent {
+	event := &gviper.NotifyEvent{
+		Kind:        l.Kind,
+		ConfigName:  l.ConfigName,
+		File:        l.File,
+		Version:     l.Version,
+		ChangedKeys: l.ChangedKeys,
+		Duration:    l.Duration,
+		Attempt:     l.Attempt,
+		Time:        l.Time,
+		Host:        l.Host,
+		PID:         l.PID,
+	}
+	if l.Error != "" {
+		event.Err = errors.New(l.Error)
+	}
+	return event
+}
@@ -0,0 +1,212 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ace-zhaoy/gviper"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type deliverFunc func(event *gviper.NotifyEvent) error
+
+func (f deliverFunc) Deliver(event *gviper.NotifyEvent) error {
+	return f(event)
+}
+
+func newTestRetryNotifier(deliverer Deliverer, options ...RetryOption) (*RetryNotifier, *[]time.Duration) {
+	var sleeps []time.Duration
+	r := NewRetryNotifier(deliverer, options...)
+	r.sleep = func(d time.Duration) bool {
+		sleeps = append(sleeps, d)
+		return true
+	}
+	return r, &sleeps
+}
+
+func TestRetryNotifier_backoff(t *testing.T) {
+	calls := 0
+	r, sleeps := newTestRetryNotifier(deliverFunc(func(event *gviper.NotifyEvent) error {
+		calls++
+		if calls < 4 {
+			return &StatusError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	}), WithBackoff(time.Second, 3*time.Second))
+
+	r.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyReloadFailed, ConfigName: "server", Err: fmt.Errorf("test error")})
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, *sleeps)
+}
+
+func TestRetryNotifier_maxAttempts(t *testing.T) {
+	calls := 0
+	r, sleeps := newTestRetryNotifier(deliverFunc(func(event *gviper.NotifyEvent) error {
+		calls++
+		return fmt.Errorf("connection refused")
+	}), WithMaxAttempts(3), WithBackoff(time.Millisecond, time.Second))
+
+	r.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyReloadFailed, ConfigName: "server", Err: fmt.Errorf("test error")})
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond}, *sleeps)
+}
+
+func TestRetryNotifier_skipsSuccess(t *testing.T) {
+	var kinds []gviper.NotifyKind
+	r, _ := newTestRetryNotifier(deliverFunc(func(event *gviper.NotifyEvent) error {
+		kinds = append(kinds, event.Kind)
+		return nil
+	}))
+
+	r.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyReloadSucceeded, ConfigName: "server"})
+	r.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyRecovered, ConfigName: "server"})
+	assert.Equal(t, []gviper.NotifyKind{gviper.NotifyRecovered}, kinds)
+}
+
+func TestRetryNotifier_FeishuBotHook(t *testing.T) {
+	var bodies []string
+	statuses := []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusOK}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Failed to read request body: %v", err)
+		}
+		bodies = append(bodies, string(body))
+		status := statuses[0]
+		statuses = statuses[1:]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "7")
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "msg": "ok"})
+	}))
+	defer ts.Close()
+
+	r, sleeps := newTestRetryNotifier(NewFeishuBotHook(ts.URL), WithBackoff(time.Second, time.Minute))
+	r.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyReloadFailed, ConfigName: "server", Err: fmt.Errorf(`bad "port"`)})
+	assert.Equal(t, []time.Duration{7 * time.Second, 2 * time.Second}, *sleeps)
+	assert.Equal(t, 3, len(bodies))
+
+	statuses = []int{http.StatusTooManyRequests, http.StatusOK}
+	r, sleeps = newTestRetryNotifier(NewFeishuBotHook(ts.URL), WithBackoff(time.Second, 5*time.Second))
+	r.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyReloadFailed, ConfigName: "server", Err: fmt.Errorf(`bad "port"`)})
+	assert.Equal(t, []time.Duration{5 * time.Second}, *sleeps)
+	assert.Equal(t, 5, len(bodies))
+	assert.Equal(t, `{"msg_type":"text","content":{"text":"Config server reload failed: bad \"port\""}}`, bodies[2])
+}
+
+func TestRetryNotifier_deadLetter(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	deadLetterFile := filepath.Join(d, "alerts.jsonl")
+
+	var texts []string
+	down := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload struct {
+			Content struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Failed to decode request body: %v", err)
+		}
+		texts = append(texts, payload.Content.Text)
+		if strings.Contains(payload.Content.Text, "log") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": 19001, "msg": "param invalid"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "msg": "ok"})
+	}))
+	defer ts.Close()
+
+	r, sleeps := newTestRetryNotifier(NewFeishuBotHook(ts.URL), WithDeadLetterFile(deadLetterFile))
+	assert.NoError(t, r.Replay())
+	r.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyReloadFailed, ConfigName: "server", Err: fmt.Errorf("test error"), File: "server.yaml", Attempt: 2})
+	r.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyRecovered, ConfigName: "log"})
+	assert.Equal(t, 0, len(*sleeps))
+
+	data, err := os.ReadFile(deadLetterFile)
+	if err != nil {
+		t.Fatalf("Failed to read dead letter file: %v", err)
+	}
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+
+	down = false
+	r, _ = newTestRetryNotifier(NewFeishuBotHook(ts.URL), WithDeadLetterFile(deadLetterFile))
+	err = r.Replay()
+	assert.EqualError(t, err, "1 dead letters still undelivered")
+	assert.Equal(t, []string{"Config server reload failed: test error", "Config log recovered"}, texts)
+
+	data, err = os.ReadFile(deadLetterFile)
+	if err != nil {
+		t.Fatalf("Failed to read dead letter file: %v", err)
+	}
+	var letter deadLetter
+	err = json.Unmarshal(data, &letter)
+	if err != nil {
+		t.Fatalf("Failed to decode dead letter: %v", err)
+	}
+	assert.Equal(t, "log", letter.ConfigName)
+	assert.Equal(t, gviper.NotifyRecovered, letter.Kind)
+}
+
+func TestRetryNotifier_Close(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	deadLetterFile := filepath.Join(d, "alerts.jsonl")
+
+	attempted := make(chan struct{}, 10)
+	r := NewRetryNotifier(deliverFunc(func(event *gviper.NotifyEvent) error {
+		attempted <- struct{}{}
+		return fmt.Errorf("connection refused")
+	}), WithBackoff(time.Hour, time.Hour), WithDeadLetterFile(deadLetterFile))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.NotifyEvent(&gviper.NotifyEvent{Kind: gviper.NotifyReloadFailed, ConfigName: "server", Err: fmt.Errorf("test error")})
+	}()
+	<-attempted
+	r.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Close to interrupt the backoff")
+	}
+	assert.Equal(t, 0, len(attempted))
+
+	data, err := os.ReadFile(deadLetterFile)
+	if err != nil {
+		t.Fatalf("Failed to read dead letter file: %v", err)
+	}
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+	r.Close()
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(fmt.Errorf("connection refused")))
+	assert.True(t, IsRetryable(&StatusError{StatusCode: http.StatusTooManyRequests}))
+	assert.True(t, IsRetryable(&StatusError{StatusCode: http.StatusInternalServerError}))
+	assert.False(t, IsRetryable(&StatusError{StatusCode: http.StatusBadRequest}))
+	assert.False(t, IsRetryable(&StatusError{StatusCode: http.StatusOK, Code: 19001, Msg: "param invalid"}))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon"))
+	d := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.True(t, d > 59*time.Minute && d <= time.Hour)
+}
@@ -1,6 +1,9 @@
 package gviper
 
-import "github.com/spf13/viper"
+import (
+	"github.com/spf13/viper"
+	"time"
+)
 
 type Option func(*Config)
 
@@ -22,6 +25,27 @@ func WithNotification(notifications ...Notification) Option {
 	}
 }
 
+func WithEventNotifier(notifiers ...EventNotifier) Option {
+	return func(config *Config) {
+		config.eventNotifiers = append(config.eventNotifiers, notifiers...)
+	}
+}
+
+// WithNotifyQueue sets the size of the notification queue and the number of
+// workers delivering from it. Zero workers deliver synchronously.
+func WithNotifyQueue(size int, workers int) Option {
+	return func(config *Config) {
+		config.notifyQueueSize = size
+		config.notifyWorkers = workers
+	}
+}
+
+func WithNotifyOverflow(policy OverflowPolicy) Option {
+	return func(config *Config) {
+		config.notifyOverflow = policy
+	}
+}
+
 func WithAutomaticEnv() Option {
 	return func(config *Config) {
 		config.AutomaticEnv()
@@ -34,8 +58,42 @@ func WithAllowEmptyEnv(allowEmptyEnv bool) Option {
 	}
 }
 
+func WithEnvPrefix(prefix string) Option {
+	return func(config *Config) {
+		config.SetEnvPrefix(prefix)
+	}
+}
+
 func WithDecoderConfigOptions(options ...viper.DecoderConfigOption) Option {
 	return func(config *Config) {
 		config.decoderConfigOptions = append(config.decoderConfigOptions, options...)
 	}
 }
+
+func WithLoadPolicy(policy LoadPolicy) Option {
+	return func(config *Config) {
+		config.loadPolicy = policy
+	}
+}
+
+func WithRemovePolicy(policy RemovePolicy) Option {
+	return func(config *Config) {
+		config.removePolicy = policy
+	}
+}
+
+func WithStrictDecode() Option {
+	return WithDecoderConfigOptions(StrictDecode())
+}
+
+func WithWarnUnusedKeys() Option {
+	return WithDecoderConfigOptions(WarnUnusedKeys())
+}
+
+// WithWatchDebounce coalesces the events of a file and reloads it once no
+// event has been seen for d. Zero reloads on every event.
+func WithWatchDebounce(d time.Duration) Option {
+	return func(config *Config) {
+		config.watchDebounce = d
+	}
+}
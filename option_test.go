@@ -2,6 +2,7 @@ package gviper
 
 import (
 	"testing"
+	"time"
 )
 
 func TestWithConfigPath(t *testing.T) {
@@ -41,12 +42,12 @@ func TestWithAutomaticEnv(t *testing.T) {
 	config1 := NewConfigWithOptions()
 	config2 := NewConfigWithOptions(WithAutomaticEnv())
 
-	if config1.viper.Get("test_env") != nil {
-		t.Errorf("Expected test_env to be nil, got %s", config1.viper.Get("test_env"))
+	if config1.viper.Load().Get("test_env") != nil {
+		t.Errorf("Expected test_env to be nil, got %s", config1.viper.Load().Get("test_env"))
 	}
 
-	if config2.viper.Get("test_env") != "test" {
-		t.Errorf("Expected test_env to be test, got %s", config2.viper.Get("test_env"))
+	if config2.viper.Load().Get("test_env") != "test" {
+		t.Errorf("Expected test_env to be test, got %s", config2.viper.Load().Get("test_env"))
 	}
 
 }
@@ -60,11 +61,21 @@ func TestWithAllowEmptyEnv(t *testing.T) {
 		WithAllowEmptyEnv(true),
 	)
 
-	if config1.viper.Get("test_env_2") != nil {
-		t.Errorf("Expected test_env_2 to be nil, got %s", config1.viper.Get("test_env_2"))
+	if config1.viper.Load().Get("test_env_2") != nil {
+		t.Errorf("Expected test_env_2 to be nil, got %s", config1.viper.Load().Get("test_env_2"))
 	}
 
-	if config2.viper.Get("test_env_2") != "" {
-		t.Errorf("Expected test_env_2 to be '', got %s", config2.viper.Get("test_env_2"))
+	if config2.viper.Load().Get("test_env_2") != "" {
+		t.Errorf("Expected test_env_2 to be '', got %s", config2.viper.Load().Get("test_env_2"))
+	}
+}
+
+func TestWithWatchDebounce(t *testing.T) {
+	config := &Config{}
+	option := WithWatchDebounce(time.Second)
+	option(config)
+
+	if config.watchDebounce != time.Second {
+		t.Errorf("Expected watch debounce to be 1s, got %s", config.watchDebounce)
 	}
 }
@@ -0,0 +1,50 @@
+package gviper
+
+import (
+	"context"
+	"github.com/ace-zhaoy/go-utils/uslice"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+type signalReloader struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// ReloadOnSignal calls ReloadAll whenever one of signals is received, SIGHUP
+// if none is given. Failures are sent to the notifications. It returns right
+// away; handling stops when ctx is canceled or Close is called. It can be used
+// together with Watch.
+func (c *Config) ReloadOnSignal(ctx context.Context, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	r := &signalReloader{done: make(chan struct{})}
+	ctx, r.cancel = context.WithCancel(ctx)
+	c.watchMu.Lock()
+	c.signalReloaders = append(c.signalReloaders, r)
+	c.watchMu.Unlock()
+
+	go func() {
+		defer close(r.done)
+		defer signal.Stop(ch)
+		defer func() {
+			c.watchMu.Lock()
+			defer c.watchMu.Unlock()
+			c.signalReloaders = uslice.Filter(c.signalReloaders, func(v *signalReloader) bool { return v != r })
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				_ = c.ReloadAll()
+			}
+		}
+	}()
+}
@@ -0,0 +1,72 @@
+package gviper
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestConfig_ReloadOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not supported on windows")
+	}
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	notified := make(chan string, 10)
+	config := NewConfigWithOptions(
+		WithConfigPath(d),
+		WithNotification(notificationFunc(func(configName string, err error) { notified <- configName })),
+	)
+	config.Register("server")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	config.ReloadOnSignal(ctx)
+	defer config.Close()
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("Failed to find process: %v", err)
+	}
+	err = os.WriteFile(serverConfigFile, []byte("name: gviper2"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	err = p.Signal(syscall.SIGHUP)
+	if err != nil {
+		t.Fatalf("Failed to send signal: %v", err)
+	}
+	assert.Eventually(t, func() bool { return config.GetString("server.name") == "gviper2" }, time.Second, 5*time.Millisecond)
+
+	err = os.WriteFile(serverConfigFile, []byte("name: ["), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	err = p.Signal(syscall.SIGHUP)
+	if err != nil {
+		t.Fatalf("Failed to send signal: %v", err)
+	}
+	select {
+	case configName := <-notified:
+		assert.Equal(t, "server", configName)
+	case <-time.After(time.Second):
+		t.Fatal("Expected notification")
+	}
+	assert.Equal(t, "gviper2", config.GetString("server.name"))
+
+	assert.NoError(t, config.Close())
+}
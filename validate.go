@@ -0,0 +1,180 @@
+package gviper
+
+import (
+	"fmt"
+	"github.com/ace-zhaoy/errors"
+	"github.com/ace-zhaoy/go-utils/usafe"
+	"github.com/ace-zhaoy/go-utils/uslice"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type Validator func(viper *viper.Viper) error
+
+// validatable is implemented by bound structs that check themselves after decoding.
+type validatable interface {
+	Validate() error
+}
+
+// Validate registers a validator that runs on every load before anything is
+// published. A failing validator rejects the load and keeps the previous
+// settings and bound values live.
+func (c *Config) Validate(name string, validator Validator) {
+	cp := c.resolveConfigParam(name)
+	cp.validators = append(cp.validators, validator)
+}
+
+func (c *Config) runValidators(cp *configParam, v *viper.Viper) error {
+	errs := make([]error, 0)
+	uslice.ForEach(cp.validators, func(validator Validator) {
+		var err error
+		if e := usafe.Func(func() { err = validator(v) }); e != nil {
+			err = e
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	})
+	return errors.Wrap(errors.Join(errs...), "validate config [%s] failed", cp.configName)
+}
+
+// validateData checks the `validate` tags of a decoded value and then calls its
+// Validate method. Keys in the errors are prefixed with the config name.
+func (c *Config) validateData(cp *configParam, b *binding, data any) error {
+	errs := validateTags(reflect.ValueOf(data), b.tagName, cp.configName, cp.configFile)
+	if v, ok := data.(validatable); ok {
+		errs = append(errs, v.Validate())
+	}
+	return errors.Join(errs...)
+}
+
+func validateTags(v reflect.Value, tagName string, prefix string, file string) []error {
+	errs := make([]error, 0)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return errs
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return errs
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, squash, skip := fieldKey(f, tagName)
+		if skip {
+			continue
+		}
+		path := prefix
+		if !squash {
+			path = joinKey(prefix, name)
+		}
+		if rules, ok := f.Tag.Lookup("validate"); ok {
+			if err := validateField(v.Field(i), rules); err != nil {
+				errs = append(errs, &ValidationError{Key: path, File: file, Rule: err.rule, Err: err.err})
+			}
+		}
+		if isStructField(f.Type) {
+			errs = append(errs, validateTags(v.Field(i), tagName, path, file)...)
+		}
+	}
+	return errs
+}
+
+type ruleError struct {
+	rule string
+	err  error
+}
+
+// validateField checks rules such as "required,min=1,max=65535" or
+// "oneof=debug info warn" and returns the first one that fails.
+func validateField(v reflect.Value, rules string) *ruleError {
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if v.IsZero() {
+				return &ruleError{rule, errors.New("value is required")}
+			}
+			continue
+		case "omitempty":
+			if v.IsZero() {
+				return nil
+			}
+			continue
+		}
+
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		var err error
+		switch name {
+		case "min":
+			err = checkBound(v, param, func(value, bound float64) bool { return value >= bound }, "less than")
+		case "max":
+			err = checkBound(v, param, func(value, bound float64) bool { return value <= bound }, "greater than")
+		case "oneof":
+			value := fmt.Sprint(v.Interface())
+			if !uslice.Contains(strings.Fields(param), value) {
+				err = errors.NewWithMessage("value %s is not one of [%s]", value, param)
+			}
+		default:
+			err = errors.NewWithMessage("unknown validation rule")
+		}
+		if err != nil {
+			return &ruleError{rule, err}
+		}
+	}
+	return nil
+}
+
+// checkBound compares numbers by value and strings, slices and maps by
+// length. Durations accept bounds such as "1s".
+func checkBound(v reflect.Value, param string, ok func(value, bound float64) bool, violation string) error {
+	var value float64
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		value = float64(v.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = v.Float()
+	default:
+		return errors.NewWithMessage("rule not supported for %s", v.Type())
+	}
+
+	var bound float64
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := cast.ToDurationE(param)
+		if err != nil {
+			return errors.Wrap(err, "invalid bound %s", param)
+		}
+		bound = float64(d)
+	} else {
+		b, err := cast.ToFloat64E(param)
+		if err != nil {
+			return errors.Wrap(err, "invalid bound %s", param)
+		}
+		bound = b
+	}
+	if !ok(value, bound) {
+		return errors.NewWithMessage("value %v is %s %s", v.Interface(), violation, param)
+	}
+	return nil
+}
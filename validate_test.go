@@ -0,0 +1,187 @@
+package gviper
+
+import (
+	"context"
+	"github.com/ace-zhaoy/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type validatedServer struct {
+	Name string `json:"name"`
+	Port int    `json:"port"`
+}
+
+func (s *validatedServer) Validate() error {
+	if s.Port <= 0 {
+		return errors.NewWithMessage("invalid port %d", s.Port)
+	}
+	return nil
+}
+
+func TestConfig_Validate(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	errEmptyName := errors.New("empty name")
+	calls := 0
+	config := NewConfig(d)
+	config.Validate("server", func(v *viper.Viper) error {
+		if v.GetString("name") == "" {
+			return errEmptyName
+		}
+		return nil
+	})
+	config.OnChange("server", func(v *viper.Viper) error {
+		calls++
+		return nil
+	})
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, 1, calls)
+
+	err = os.WriteFile(serverConfigFile, []byte("name: ''\nenv: test"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	err = config.Load()
+	assert.True(t, errors.Is(err, errors.NewWithMessage("validate config [%s] failed", "server")))
+	assert.True(t, errors.Is(err, errEmptyName))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "gviper", config.GetString("server.name"))
+	assert.Equal(t, false, config.IsSet("server.env"))
+}
+
+func TestConfig_Validate_boundStruct(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper\nport: 8080"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	ch := make(chan error, 1)
+	var myServer validatedServer
+	config := NewConfig(d)
+	config.Bind("server", &myServer)
+	server := BindValue[validatedServer](config, "server")
+	config.RegisterNotification(notificationFunc(func(configName string, err error) { ch <- err }))
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+
+	err = replaceFile(serverConfigFile, []byte("name: gviper2\nport: -1"))
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	select {
+	case err = <-ch:
+		assert.True(t, errors.Is(err, errors.NewWithMessage("validate config [%s] failed", "server")))
+	case <-time.After(time.Second):
+		t.Fatal("Expected notification")
+	}
+	assert.Equal(t, validatedServer{Name: "gviper", Port: 8080}, myServer)
+	assert.Equal(t, &validatedServer{Name: "gviper", Port: 8080}, server.Load())
+	assert.Equal(t, "gviper", config.GetString("server.name"))
+}
+
+func TestConfig_Validate_tags(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	databaseConfigFile := filepath.Join(d, "database.yaml")
+	err := os.WriteFile(databaseConfigFile, []byte(`
+host: localhost
+port: 5432
+level: info
+timeout: 3s
+pool:
+  max_open: 10
+`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create database.yaml: %v", err)
+	}
+
+	type Pool struct {
+		MaxOpen int `json:"max_open" validate:"min=1,max=100"`
+	}
+	type Database struct {
+		Host    string        `json:"host" validate:"required"`
+		Port    int           `json:"port" validate:"required,min=1,max=65535"`
+		Level   string        `json:"level" validate:"oneof=debug info warn"`
+		Timeout time.Duration `json:"timeout" validate:"min=1s"`
+		Tags    []string      `json:"tags" validate:"omitempty,min=2"`
+		Pool    *Pool         `json:"pool" validate:"required"`
+	}
+
+	config := NewConfig(d)
+	database := BindValue[Database](config, "database")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, 10, database.Load().Pool.MaxOpen)
+
+	err = os.WriteFile(databaseConfigFile, []byte(`
+port: 70000
+level: trace
+timeout: 10ms
+tags: [a]
+pool:
+  max_open: 0
+`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write database.yaml: %v", err)
+	}
+	err = config.Load()
+	assert.True(t, errors.Is(err, errors.NewWithMessage("validate config [%s] failed", "database")))
+	assert.True(t, errors.Is(err, ErrValidation))
+	assert.Contains(t, err.Error(), "key [database.host] in config file ["+databaseConfigFile+"] failed rule [required]")
+	assert.Contains(t, err.Error(), "key [database.port] in config file ["+databaseConfigFile+"] failed rule [max=65535]")
+	assert.Contains(t, err.Error(), "key [database.level] in config file ["+databaseConfigFile+"] failed rule [oneof=debug info warn]")
+	assert.Contains(t, err.Error(), "key [database.timeout] in config file ["+databaseConfigFile+"] failed rule [min=1s]")
+	assert.Contains(t, err.Error(), "key [database.tags] in config file ["+databaseConfigFile+"] failed rule [min=2]")
+	assert.Contains(t, err.Error(), "key [database.pool.max_open] in config file ["+databaseConfigFile+"] failed rule [min=1]")
+	assert.Equal(t, 10, database.Load().Pool.MaxOpen)
+
+	err = os.WriteFile(databaseConfigFile, []byte("host: localhost\nport: 5432\nlevel: warn\ntimeout: 1s"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write database.yaml: %v", err)
+	}
+	err = config.Load()
+	assert.Contains(t, err.Error(), "key [database.pool] in config file ["+databaseConfigFile+"] failed rule [required]")
+}
+
+func TestValidateField(t *testing.T) {
+	type Fields struct {
+		Name    string `json:"name" validate:"bogus"`
+		Mode    *int   `json:"mode" validate:"min=1"`
+		Enabled bool   `json:"enabled" validate:"min=1"`
+	}
+	errs := validateTags(reflect.ValueOf(&Fields{}), "json", "app", "app.yaml")
+	assert.Equal(t, 2, len(errs))
+	assert.Contains(t, errs[0].Error(), "key [app.name] in config file [app.yaml] failed rule [bogus]: unknown validation rule")
+	assert.Contains(t, errs[1].Error(), "key [app.enabled] in config file [app.yaml] failed rule [min=1]: rule not supported for bool")
+
+	mode := 0
+	errs = validateTags(reflect.ValueOf(&Fields{Name: "a", Mode: &mode}), "json", "app", "app.yaml")
+	assert.Contains(t, errs[1].Error(), "key [app.mode] in config file [app.yaml] failed rule [min=1]: value 0 is less than 1")
+}
@@ -0,0 +1,48 @@
+package gviper
+
+import (
+	"github.com/spf13/viper"
+	"reflect"
+	"sync/atomic"
+)
+
+// Value holds the latest decoded snapshot of a bound config. Every reload
+// decodes into a fresh T and publishes it atomically, so a pointer returned
+// by Load is never modified afterwards and is safe to share between goroutines.
+type Value[T any] struct {
+	p atomic.Pointer[T]
+}
+
+func newValue[T any]() *Value[T] {
+	v := &Value[T]{}
+	v.p.Store(new(T))
+	return v
+}
+
+func BindValue[T any](c *Config, name string) *Value[T] {
+	return BindValueWithTag[T](c, name, "json")
+}
+
+func BindValueWithTag[T any](c *Config, name string, tagName string, decoderConfigOptions ...viper.DecoderConfigOption) *Value[T] {
+	v := newValue[T]()
+	c.bind(name, v, tagName, decoderConfigOptions...)
+	return v
+}
+
+// Load returns the current snapshot. It returns a pointer to the zero value
+// before the config is loaded. The returned value must be treated as read-only.
+func (v *Value[T]) Load() *T {
+	return v.p.Load()
+}
+
+func (v *Value[T]) dataType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (v *Value[T]) prepare(vp *viper.Viper, decoderConfigOptions ...viper.DecoderConfigOption) (any, func(), error) {
+	data := new(T)
+	if err := vp.Unmarshal(data, decoderConfigOptions...); err != nil {
+		return nil, nil, err
+	}
+	return data, func() { v.p.Store(data) }, nil
+}
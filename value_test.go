@@ -0,0 +1,98 @@
+package gviper
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBindValue(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	type MyServer struct {
+		Name string `json:"name"`
+		Env  string `json:"env"`
+	}
+
+	config := NewConfig(d)
+	server := BindValue[MyServer](config, "server")
+	assert.Equal(t, &MyServer{}, server.Load())
+
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+
+	first := server.Load()
+	assert.Equal(t, &MyServer{Name: "gviper"}, first)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = server.Load().Env
+			}
+		}
+	}()
+
+	f1, err := os.OpenFile(serverConfigFile, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open server.yaml: %v", err)
+	}
+	_, err = f1.WriteString("\nenv: test")
+	if err != nil {
+		t.Fatalf("Failed to write to server.yaml: %v", err)
+	}
+	_ = f1.Close()
+
+	assert.Eventually(t, func() bool { return server.Load().Env == "test" }, time.Second, 5*time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	assert.Equal(t, &MyServer{Name: "gviper", Env: "test"}, server.Load())
+	assert.Equal(t, &MyServer{Name: "gviper"}, first)
+}
+
+func TestBindValueWithTag(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	err := os.WriteFile(filepath.Join(d, "server.yaml"), []byte("name: gviper\ntimeout: 3s"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	type MyServer struct {
+		Name    string        `yaml:"name"`
+		Timeout time.Duration `yaml:"timeout"`
+	}
+
+	config := NewConfig(d)
+	server := BindValueWithTag[MyServer](config, "server", "yaml")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	assert.Equal(t, &MyServer{Name: "gviper", Timeout: 3 * time.Second}, server.Load())
+}
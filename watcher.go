@@ -0,0 +1,182 @@
+package gviper
+
+import (
+	"context"
+	"github.com/ace-zhaoy/errors"
+	"github.com/ace-zhaoy/go-utils/uslice"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type watcher struct {
+	ctx       context.Context
+	fsWatcher *fsnotify.Watcher
+	realFiles map[*configParam]string
+	timers    map[*configParam]*time.Timer
+	fire      chan *configParam
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// Watch starts watching every registered file and reloads it when it changes.
+// It returns once the watcher is set up; watching stops when ctx is canceled
+// or Close is called.
+func (c *Config) Watch(ctx context.Context) (err error) {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	if c.watcher != nil {
+		return errors.New("config is already being watched")
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "create watcher failed")
+	}
+	defer func() {
+		if err != nil {
+			_ = fsWatcher.Close()
+		}
+	}()
+
+	w := &watcher{
+		fsWatcher: fsWatcher,
+		realFiles: make(map[*configParam]string, len(c.configs)),
+		timers:    make(map[*configParam]*time.Timer, len(c.configs)),
+		fire:      make(chan *configParam),
+		done:      make(chan struct{}),
+	}
+	dirs := make(map[string]struct{})
+	for _, cp := range c.configs {
+		w.realFiles[cp], _ = filepath.EvalSymlinks(cp.configFile)
+		// the whole directory is watched to pick up atomic saves and renames
+		dir := filepath.Dir(cp.configFile)
+		if _, ok := dirs[dir]; ok {
+			continue
+		}
+		dirs[dir] = struct{}{}
+		if err = fsWatcher.Add(dir); err != nil {
+			return errors.Wrap(err, "watch dir [%s] failed", dir)
+		}
+	}
+
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	c.watcher = w
+	go c.watchLoop(w)
+	return nil
+}
+
+// Close stops watching, signal handling and notification dispatch. It must be
+// called once a Config started with Watch or ReloadOnSignal is no longer
+// needed, or their goroutines leak. It blocks until those goroutines have
+// exited, so it must not be called from a listener or notifier; use Stop
+// there. Queued notifications are discarded, and later ones are delivered
+// synchronously.
+func (c *Config) Close() error {
+	w, reloaders := c.stop()
+	uslice.ForEach(reloaders, func(r *signalReloader) { <-r.done })
+	if w != nil {
+		<-w.done
+	}
+	return nil
+}
+
+// Stop is like Close but does not wait for the goroutines to exit, so a
+// listener or notifier may call it. A reload in progress still finishes.
+func (c *Config) Stop() {
+	c.stop()
+}
+
+func (c *Config) stop() (*watcher, []*signalReloader) {
+	c.watchMu.Lock()
+	w := c.watcher
+	reloaders := append([]*signalReloader(nil), c.signalReloaders...)
+	c.watchMu.Unlock()
+	uslice.ForEach(reloaders, func(r *signalReloader) { r.cancel() })
+	if w != nil {
+		w.cancel()
+	}
+	c.closeDispatcher()
+	return w, reloaders
+}
+
+func (c *Config) watchLoop(w *watcher) {
+	defer close(w.done)
+	defer w.fsWatcher.Close()
+	defer func() {
+		for _, t := range w.timers {
+			t.Stop()
+		}
+	}()
+	defer func() {
+		c.watchMu.Lock()
+		defer c.watchMu.Unlock()
+		if c.watcher == w {
+			c.watcher = nil
+		}
+	}()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case cp := <-w.fire:
+			c.reloadChanged(cp)
+		case event, ok := <-w.fsWatcher.Events:
+			if !ok {
+				return
+			}
+			c.handleEvent(w, event)
+		case err, ok := <-w.fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			c.dispatch(newNotifyEvent(NotifyWatchError, nil, errors.Wrap(err, "watch config error")))
+		}
+	}
+}
+
+func (c *Config) handleEvent(w *watcher, event fsnotify.Event) {
+	name := filepath.Clean(event.Name)
+	uslice.ForEach(c.configs, func(cp *configParam) {
+		realFile, _ := filepath.EvalSymlinks(cp.configFile)
+		// reload when the file itself was written, created or removed, or when
+		// the real path behind it changed (e.g. a k8s ConfigMap symlink swap)
+		if (name == filepath.Clean(cp.configFile) && !event.Has(fsnotify.Chmod)) ||
+			(realFile != "" && realFile != w.realFiles[cp]) {
+			w.realFiles[cp] = realFile
+			c.schedule(w, cp)
+		}
+	})
+}
+
+// schedule reloads cp right away, or once its events settle when a debounce
+// window is configured. Timers hand the reload back to the watch goroutine,
+// so reloads never run after the watcher stopped.
+func (c *Config) schedule(w *watcher, cp *configParam) {
+	if c.watchDebounce <= 0 {
+		c.reloadChanged(cp)
+		return
+	}
+	if t, ok := w.timers[cp]; ok {
+		t.Reset(c.watchDebounce)
+		return
+	}
+	w.timers[cp] = time.AfterFunc(c.watchDebounce, func() {
+		select {
+		case w.fire <- cp:
+		case <-w.ctx.Done():
+		}
+	})
+}
+
+// reloadChanged checks whether the file exists when the reload runs rather
+// than trusting the event, so a remove followed by a create, as done by
+// editors saving atomically, is just a reload.
+func (c *Config) reloadChanged(cp *configParam) {
+	if _, err := os.Stat(cp.configFile); os.IsNotExist(err) {
+		_ = c.remove(cp)
+		return
+	}
+	_ = c.load(cp, false)
+}
@@ -0,0 +1,356 @@
+package gviper
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfig_Watch_error(t *testing.T) {
+	config := NewConfig(filepath.Join(t.TempDir(), "missing"), "server")
+	err := config.Watch(context.Background())
+	assert.Error(t, err)
+	assert.NoError(t, config.Close())
+}
+
+func TestConfig_Watch_twice(t *testing.T) {
+	config := NewConfig(t.TempDir(), "server")
+	err := config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+	assert.Error(t, config.Watch(context.Background()))
+}
+
+func TestConfig_Close(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	config := NewConfig(d, "server")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+
+	err = replaceFile(serverConfigFile, []byte("name: gviper2"))
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	assert.Eventually(t, func() bool { return config.GetString("server.name") == "gviper2" }, time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, config.Close())
+	assert.NoError(t, config.Close())
+	err = replaceFile(serverConfigFile, []byte("name: gviper3"))
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "gviper2", config.GetString("server.name"))
+
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+	err = replaceFile(serverConfigFile, []byte("name: gviper4"))
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	assert.Eventually(t, func() bool { return config.GetString("server.name") == "gviper4" }, time.Second, 5*time.Millisecond)
+}
+
+func TestConfig_Watch_contextCanceled(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	config := NewConfig(d, "server")
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	err = config.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	w := config.watcher
+	cancel()
+	select {
+	case <-w.done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected watch goroutine to exit")
+	}
+
+	err = replaceFile(serverConfigFile, []byte("name: gviper2"))
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "gviper", config.GetString("server.name"))
+	assert.NoError(t, config.Close())
+
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	assert.NoError(t, config.Close())
+}
+
+func TestConfig_Watch_debounce(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	ch := make(chan string, 10)
+	config := NewConfigWithOptions(WithConfigPath(d), WithWatchDebounce(50*time.Millisecond))
+	config.OnChangeEvent("server", func(event *ChangeEvent) error {
+		ch <- event.Viper().GetString("name")
+		return nil
+	})
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, "gviper", <-ch)
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+
+	for i := 0; i < 5; i++ {
+		err = os.WriteFile(serverConfigFile, []byte("name: gviper"+string(rune('a'+i))), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write server.yaml: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	select {
+	case name := <-ch:
+		assert.Equal(t, "gvipere", name)
+	case <-time.After(time.Second):
+		t.Fatal("Expected reload")
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, len(ch))
+}
+
+func TestConfig_Watch_unchangedContent(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	ch := make(chan string, 10)
+	config := NewConfig(d)
+	config.OnChangeEvent("server", func(event *ChangeEvent) error {
+		ch <- event.Viper().GetString("name")
+		return nil
+	})
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	assert.Equal(t, "gviper", <-ch)
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+
+	err = replaceFile(serverConfigFile, []byte("name: gviper"))
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	err = os.Chmod(serverConfigFile, 0600)
+	if err != nil {
+		t.Fatalf("Failed to chmod server.yaml: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, len(ch))
+
+	err = replaceFile(serverConfigFile, []byte("name: gviper2"))
+	if err != nil {
+		t.Fatalf("Failed to write server.yaml: %v", err)
+	}
+	select {
+	case name := <-ch:
+		assert.Equal(t, "gviper2", name)
+	case <-time.After(time.Second):
+		t.Fatal("Expected reload")
+	}
+}
+
+func TestConfig_Watch_lateFile(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+
+	ch := make(chan *ChangeEvent, 10)
+	config := NewConfig(d)
+	config.RegisterOptional("local")
+	config.OnChangeEvent("local", func(event *ChangeEvent) error {
+		ch <- event
+		return nil
+	})
+	err := config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	<-ch
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+
+	err = replaceFile(filepath.Join(d, "local.yaml"), []byte("port: 7070"))
+	if err != nil {
+		t.Fatalf("Failed to create local.yaml: %v", err)
+	}
+	select {
+	case event := <-ch:
+		assert.Equal(t, ChangeUpdated, event.Kind)
+		assert.Equal(t, []string{"port"}, event.Added)
+	case <-time.After(time.Second):
+		t.Fatal("Expected reload")
+	}
+	assert.Equal(t, 7070, config.GetInt("local.port"))
+}
+
+func TestConfig_Watch_removeKeep(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	ch := make(chan *ChangeEvent, 10)
+	config := NewConfig(d, "server")
+	var server struct {
+		Name string `json:"name"`
+	}
+	config.Bind("server", &server)
+	var kinds []NotifyKind
+	config.RegisterEventNotifier(eventNotifierFunc(func(event *NotifyEvent) { kinds = append(kinds, event.Kind) }))
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.OnChangeEvent("server", func(event *ChangeEvent) error {
+		ch <- event
+		return nil
+	})
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+
+	err = os.Remove(serverConfigFile)
+	if err != nil {
+		t.Fatalf("Failed to remove server.yaml: %v", err)
+	}
+	select {
+	case event := <-ch:
+		assert.Equal(t, ChangeRemoved, event.Kind)
+		assert.False(t, event.HasChanges())
+		assert.Equal(t, "gviper", event.Viper().GetString("name"))
+	case <-time.After(time.Second):
+		t.Fatal("Expected removed event")
+	}
+	assert.Equal(t, "gviper", config.GetString("server.name"))
+	assert.Equal(t, "gviper", server.Name)
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, []NotifyKind{NotifyRemoved}, kinds)
+
+	err = replaceFile(serverConfigFile, []byte("name: gviper"))
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+	select {
+	case event := <-ch:
+		assert.Equal(t, ChangeUpdated, event.Kind)
+	case <-time.After(time.Second):
+		t.Fatal("Expected reload")
+	}
+}
+
+func TestConfig_Watch_removeReset(t *testing.T) {
+	d := t.TempDir()
+	t.Logf("tmpdir: %s", d)
+	serverConfigFile := filepath.Join(d, "server.yaml")
+	err := os.WriteFile(serverConfigFile, []byte("name: gviper\nport: 9090"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create server.yaml: %v", err)
+	}
+
+	type Server struct {
+		Name string `json:"name"`
+		Port int    `json:"port" default:"8080"`
+	}
+	ch := make(chan *ChangeEvent, 10)
+	config := NewConfigWithOptions(WithConfigPath(d), WithRemovePolicy(RemoveReset))
+	server := BindValue[Server](config, "server")
+	var kinds []NotifyKind
+	config.RegisterEventNotifier(eventNotifierFunc(func(event *NotifyEvent) { kinds = append(kinds, event.Kind) }))
+	err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.OnChangeEvent("server", func(event *ChangeEvent) error {
+		ch <- event
+		return nil
+	})
+	err = config.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer config.Close()
+
+	err = os.Remove(serverConfigFile)
+	if err != nil {
+		t.Fatalf("Failed to remove server.yaml: %v", err)
+	}
+	select {
+	case event := <-ch:
+		assert.Equal(t, ChangeRemoved, event.Kind)
+		assert.Equal(t, []string{"name"}, event.Removed)
+		assert.Equal(t, []string{"port"}, event.Modified)
+	case <-time.After(time.Second):
+		t.Fatal("Expected removed event")
+	}
+	assert.False(t, config.IsSet("server.name"))
+	assert.Equal(t, Server{Port: 8080}, *server.Load())
+	assert.NoError(t, config.Flush(context.Background()))
+	assert.Equal(t, []NotifyKind{NotifyRemoved}, kinds)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, len(ch))
+}